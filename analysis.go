@@ -0,0 +1,133 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LayoutAnalysis describes how a layout suits a corpus of text. Loads are
+// ratios of the keys typed, bigram rates ratios of the pairs of consecutive
+// keys within words.
+type LayoutAnalysis struct {
+	Fingers     map[Finger]float64
+	LeftHand    float64
+	RightHand   float64
+	SameFinger  float64
+	RowJumps    float64
+	Alternation float64
+	// Rolls are bigrams typed with different fingers of the same hand
+	// without skipping a row.
+	Rolls float64
+	Shift float64
+	// Untypeable is the ratio of characters the layout has no key for.
+	Untypeable float64
+}
+
+func analyzeLayout(layout Layout, corpus string) LayoutAnalysis {
+	a := LayoutAnalysis{Fingers: map[Finger]float64{}}
+	chars, strokes, bigrams := 0, 0, 0
+
+	var prev *Key
+	for _, r := range corpus {
+		if unicode.IsSpace(r) {
+			prev = nil
+			continue
+		}
+		chars++
+		key, ok := layout.Keys[r]
+		if !ok {
+			a.Untypeable++
+			prev = nil
+			continue
+		}
+
+		strokes++
+		for _, finger := range FingerSequence {
+			if key.Finger&finger != 0 {
+				a.Fingers[finger]++
+			}
+		}
+		switch hand(key.Finger) {
+		case leftHand:
+			a.LeftHand++
+		case rightHand:
+			a.RightHand++
+		}
+		if key.Shift {
+			a.Shift++
+		}
+
+		if prev != nil {
+			bigrams++
+			samePosition := prev.Row == key.Row && prev.Col == key.Col
+			sameHand := hand(key.Finger) != NoFinger && hand(key.Finger) == hand(prev.Finger)
+			switch {
+			case key.Finger == prev.Finger && !samePosition:
+				a.SameFinger++
+			case sameHand && abs(key.Row-prev.Row) >= 2:
+				a.RowJumps++
+			case sameHand && key.Finger != prev.Finger:
+				a.Rolls++
+			case hand(key.Finger) != NoFinger && hand(prev.Finger) != NoFinger && !sameHand:
+				a.Alternation++
+			}
+		}
+		prev = &key
+	}
+
+	for finger, n := range a.Fingers {
+		a.Fingers[finger] = ratio(n, strokes)
+	}
+	a.LeftHand = ratio(a.LeftHand, strokes)
+	a.RightHand = ratio(a.RightHand, strokes)
+	a.Shift = ratio(a.Shift, strokes)
+	a.SameFinger = ratio(a.SameFinger, bigrams)
+	a.RowJumps = ratio(a.RowJumps, bigrams)
+	a.Rolls = ratio(a.Rolls, bigrams)
+	a.Alternation = ratio(a.Alternation, bigrams)
+	a.Untypeable = ratio(a.Untypeable, chars)
+	return a
+}
+
+// formatAnalyses prints the analyses of layouts side by side.
+func formatAnalyses(layouts []Layout, analyses []LayoutAnalysis) string {
+	var b strings.Builder
+
+	widths := make([]int, len(layouts))
+	fmt.Fprintf(&b, "%-14s", "")
+	for i, layout := range layouts {
+		widths[i] = max(8, utf8.RuneCountInString(layout.Name))
+		fmt.Fprintf(&b, " %*s", widths[i], layout.Name)
+	}
+
+	row := func(label string, value func(LayoutAnalysis) float64) {
+		fmt.Fprintf(&b, "\n%-14s", label)
+		for i, a := range analyses {
+			fmt.Fprintf(&b, " %*.1f%%", widths[i]-1, 100*value(a))
+		}
+	}
+
+	for _, finger := range FingerSequence {
+		used := false
+		for _, a := range analyses {
+			used = used || a.Fingers[finger] > 0
+		}
+		if used || (finger != LeftThumb && finger != RightThumb) {
+			finger := finger
+			row(fingerName(finger), func(a LayoutAnalysis) float64 { return a.Fingers[finger] })
+		}
+	}
+	row("left hand", func(a LayoutAnalysis) float64 { return a.LeftHand })
+	row("right hand", func(a LayoutAnalysis) float64 { return a.RightHand })
+	row("same finger", func(a LayoutAnalysis) float64 { return a.SameFinger })
+	row("row jumps", func(a LayoutAnalysis) float64 { return a.RowJumps })
+	row("alternation", func(a LayoutAnalysis) float64 { return a.Alternation })
+	row("rolls", func(a LayoutAnalysis) float64 { return a.Rolls })
+	row("shift", func(a LayoutAnalysis) float64 { return a.Shift })
+	row("untypeable", func(a LayoutAnalysis) float64 { return a.Untypeable })
+
+	return b.String()
+}
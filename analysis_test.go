@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzeLayout(t *testing.T) {
+	a := analyzeLayout(qwerty, "ded fj Aé")
+	assert.InDelta(t, 3./6, a.Fingers[LeftMiddle], 1e-9)
+	assert.InDelta(t, 1./6, a.Fingers[RightIndex], 1e-9)
+	assert.InDelta(t, 5./6, a.LeftHand, 1e-9)
+	assert.InDelta(t, 1./6, a.Shift, 1e-9)
+	assert.InDelta(t, 1./7, a.Untypeable, 1e-9)
+	// de ed fj
+	assert.InDelta(t, 2./3, a.SameFinger, 1e-9)
+	assert.InDelta(t, 1./3, a.Alternation, 1e-9)
+
+	a = analyzeLayout(qwerty, "as zr")
+	assert.InDelta(t, 1./2, a.Rolls, 1e-9)
+	assert.InDelta(t, 1./2, a.RowJumps, 1e-9)
+}
+
+func TestLayoutAnalyzeCommand(t *testing.T) {
+	state, commands := Init([]string{"gotypist", "layout", "analyze", "--layout", "qwerty,colemak", "--corpus", "corpus.txt"}, map[string]string{})
+	assert.Len(t, commands, 1)
+	assert.Equal(t, []string{"qwerty", "colemak"}, []string{state.Analysis[0].Name, state.Analysis[1].Name})
+
+	_, commands = reduce(state, CorpusData{Data: []byte("the quick brown fox jumps over the lazy dog")}, time.Unix(0, 0))
+	report := commands[0].(Exit).GoodbyeMessage
+	lines := strings.Split(report, "\n")
+	assert.Regexp(t, `^\s+qwerty\s+colemak$`, lines[0])
+	assert.Contains(t, report, "same finger")
+	assert.NotContains(t, report, "thumb")
+
+	state, commands = Init([]string{"gotypist", "layout", "analyze", "-layout", "my.layout", "-corpus", "-"}, map[string]string{})
+	assert.Len(t, commands, 2)
+	state, _ = reduce(state, LayoutData{Name: "my.layout", Data: []byte("a left-thumb 2 0\n")}, time.Unix(0, 0))
+	_, commands = reduce(state, CorpusData{Data: []byte("aa")}, time.Unix(0, 0))
+	assert.Contains(t, commands[0].(Exit).GoodbyeMessage, "left-thumb")
+
+	_, commands = Init([]string{"gotypist", "layout", "analyze"}, map[string]string{})
+	assert.IsType(t, Exit{}, commands[0])
+}
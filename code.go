@@ -0,0 +1,311 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Language describes the comment syntax of a programming language.
+type Language struct {
+	Name          string
+	LineComments  []string
+	BlockComments [][2]string
+	Quotes        string
+	// WordComments means that line comments only start at the beginning
+	// of a word, like # in shell scripts (unlike in $# or ${#x}).
+	WordComments bool
+	// Docstrings means that block comments only start a statement, like
+	// """ in Python (unlike in s = """text""", which is a string).
+	Docstrings bool
+}
+
+var (
+	cLike  = Language{LineComments: []string{"//"}, BlockComments: [][2]string{{"/*", "*/"}}, Quotes: `"'`}
+	hashes = Language{LineComments: []string{"#"}, Quotes: `"'`}
+	shell  = Language{LineComments: []string{"#"}, Quotes: `"'`, WordComments: true}
+)
+
+func like(base Language, name string) Language {
+	base.Name = name
+	return base
+}
+
+var languages = map[string]Language{
+	".c":     like(cLike, "C"),
+	".h":     like(cLike, "C"),
+	".cc":    like(cLike, "C++"),
+	".cpp":   like(cLike, "C++"),
+	".hpp":   like(cLike, "C++"),
+	".cs":    like(cLike, "C#"),
+	".go":    {Name: "Go", LineComments: []string{"//"}, BlockComments: [][2]string{{"/*", "*/"}}, Quotes: "\"'`"},
+	".java":  like(cLike, "Java"),
+	".kt":    like(cLike, "Kotlin"),
+	".scala": like(cLike, "Scala"),
+	".swift": like(cLike, "Swift"),
+	".js":    {Name: "JavaScript", LineComments: []string{"//"}, BlockComments: [][2]string{{"/*", "*/"}}, Quotes: "\"'`"},
+	".jsx":   {Name: "JavaScript", LineComments: []string{"//"}, BlockComments: [][2]string{{"/*", "*/"}}, Quotes: "\"'`"},
+	".ts":    {Name: "TypeScript", LineComments: []string{"//"}, BlockComments: [][2]string{{"/*", "*/"}}, Quotes: "\"'`"},
+	".tsx":   {Name: "TypeScript", LineComments: []string{"//"}, BlockComments: [][2]string{{"/*", "*/"}}, Quotes: "\"'`"},
+	".rs":    {Name: "Rust", LineComments: []string{"//"}, BlockComments: [][2]string{{"/*", "*/"}}, Quotes: `"`},
+	".php":   {Name: "PHP", LineComments: []string{"//", "#"}, BlockComments: [][2]string{{"/*", "*/"}}, Quotes: `"'`},
+	".css":   {Name: "CSS", BlockComments: [][2]string{{"/*", "*/"}}, Quotes: `"'`},
+	".scss":  like(cLike, "SCSS"),
+	".py":    {Name: "Python", LineComments: []string{"#"}, BlockComments: [][2]string{{`"""`, `"""`}, {"'''", "'''"}}, Quotes: `"'`, Docstrings: true},
+	".rb":    {Name: "Ruby", LineComments: []string{"#"}, BlockComments: [][2]string{{"=begin", "=end"}}, Quotes: `"'`},
+	".sh":    like(shell, "Shell"),
+	".bash":  like(shell, "Shell"),
+	".zsh":   like(shell, "Shell"),
+	".pl":    like(shell, "Perl"),
+	".r":     like(hashes, "R"),
+	".yml":   like(shell, "YAML"),
+	".yaml":  like(shell, "YAML"),
+	".toml":  like(hashes, "TOML"),
+	".ex":    like(hashes, "Elixir"),
+	".exs":   like(hashes, "Elixir"),
+	".nix":   {Name: "Nix", LineComments: []string{"#"}, BlockComments: [][2]string{{"/*", "*/"}}, Quotes: `"`},
+	".lua":   {Name: "Lua", LineComments: []string{"--"}, BlockComments: [][2]string{{"--[[", "]]"}}, Quotes: `"'`},
+	".sql":   {Name: "SQL", LineComments: []string{"--"}, BlockComments: [][2]string{{"/*", "*/"}}, Quotes: `'"`},
+	".hs":    {Name: "Haskell", LineComments: []string{"--"}, BlockComments: [][2]string{{"{-", "-}"}}, Quotes: `"`},
+	".elm":   {Name: "Elm", LineComments: []string{"--"}, BlockComments: [][2]string{{"{-", "-}"}}, Quotes: `"`},
+	".erl":   {Name: "Erlang", LineComments: []string{"%"}, Quotes: `"`},
+	".clj":   {Name: "Clojure", LineComments: []string{";"}, Quotes: `"`},
+	".lisp":  {Name: "Lisp", LineComments: []string{";"}, BlockComments: [][2]string{{"#|", "|#"}}, Quotes: `"`},
+	".el":    {Name: "Emacs Lisp", LineComments: []string{";"}, Quotes: `"`},
+	".vim":   {Name: "Vim script", LineComments: []string{`"`}, Quotes: `'`},
+	".html":  {Name: "HTML", BlockComments: [][2]string{{"<!--", "-->"}}},
+	".xml":   {Name: "XML", BlockComments: [][2]string{{"<!--", "-->"}}},
+}
+
+var languageFiles = map[string]Language{
+	"Makefile":   like(hashes, "Makefile"),
+	"Dockerfile": like(hashes, "Dockerfile"),
+}
+
+// skippedDirs are never descended into when walking source trees.
+var skippedDirs = map[string]bool{
+	"vendor": true, "node_modules": true, "third_party": true,
+	"testdata": true, "target": true, "dist": true, "build": true,
+}
+
+var generatedMarkers = [][]byte{
+	[]byte("Code generated"),
+	[]byte("DO NOT EDIT"),
+	[]byte("@generated"),
+	[]byte("autogenerated"),
+}
+
+func languageFor(path string) (Language, bool) {
+	if lang, ok := languageFiles[filepath.Base(path)]; ok {
+		return lang, true
+	}
+	lang, ok := languages[strings.ToLower(filepath.Ext(path))]
+	return lang, ok
+}
+
+func skipDir(name string) bool {
+	return skippedDirs[name] || isHidden(name)
+}
+
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+func isBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// isGenerated looks for the usual markers of generated code in the first few
+// lines, and for minified files.
+func isGenerated(path string, data []byte) bool {
+	base := filepath.Base(path)
+	if strings.Contains(base, ".min.") || strings.HasSuffix(base, ".pb.go") {
+		return true
+	}
+
+	head := data
+	for i, n := 0, 0; i < len(head); i++ {
+		if head[i] == '\n' {
+			n++
+			if n == 5 {
+				head = head[:i]
+				break
+			}
+		}
+	}
+
+	for _, marker := range generatedMarkers {
+		if bytes.Contains(head, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripComments removes all comments from source code, keeping the line
+// structure intact. Comment markers within quoted strings are ignored.
+func stripComments(lang Language, lines []string) []string {
+	stripped := make([]string, len(lines))
+	blockEnd := ""
+
+	for i, line := range lines {
+		var code strings.Builder
+		quote := rune(0)
+
+	scan:
+		for j := 0; j < len(line); {
+			rest := line[j:]
+
+			if blockEnd != "" {
+				end := strings.Index(rest, blockEnd)
+				if end < 0 {
+					break scan
+				}
+				j += end + len(blockEnd)
+				blockEnd = ""
+				continue
+			}
+
+			if quote != 0 {
+				if rest[0] == '\\' && len(rest) > 1 {
+					code.WriteString(rest[:2])
+					j += 2
+					continue
+				}
+				if rune(rest[0]) == quote {
+					quote = 0
+				}
+				code.WriteByte(rest[0])
+				j++
+				continue
+			}
+
+			for _, block := range lang.BlockComments {
+				if strings.HasPrefix(rest, block[0]) && (!lang.Docstrings || strings.TrimSpace(line[:j]) == "") {
+					blockEnd = block[1]
+					j += len(block[0])
+					continue scan
+				}
+			}
+			for _, comment := range lang.LineComments {
+				if strings.HasPrefix(rest, comment) && (!lang.WordComments || wordStart(line, j)) {
+					break scan
+				}
+			}
+			if strings.ContainsRune(lang.Quotes, rune(rest[0])) {
+				quote = rune(rest[0])
+			}
+
+			code.WriteByte(rest[0])
+			j++
+		}
+
+		stripped[i] = strings.TrimRight(code.String(), " \t")
+	}
+
+	return stripped
+}
+
+// wordStart tells whether a shell word may start at index i of line.
+func wordStart(line string, i int) bool {
+	return i == 0 || strings.ContainsRune(" \t;&|()", rune(line[i-1]))
+}
+
+// isTrivial tells whether a line has nothing worth typing, like a lone brace.
+func isTrivial(line string) bool {
+	for _, r := range line {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// codeLines extracts the lines worth typing from source code: comments and
+// trivial lines are dropped, as well as lines that repeat the previous one.
+func codeLines(lang Language, data []byte, maxLength int) []string {
+	var lines []string
+	prev := ""
+
+	for _, line := range stripComments(lang, readLines(data)) {
+		line = strings.TrimSpace(line)
+		if isTrivial(line) || line == prev || utf8.RuneCountInString(line) > maxLength {
+			continue
+		}
+		lines = append(lines, line)
+		prev = line
+	}
+
+	return lines
+}
+
+// maxBlockLines is the maximum height of a code block.
+const maxBlockLines = 10
+
+// codeBlocks extracts blocks of code, i.e. runs of lines separated by blank
+// lines, without comments. Tabs are expanded and blocks are dedented. Blocks
+// higher than maxLines are split, blocks with lines wider than maxWidth are
+// dropped.
+func codeBlocks(lang Language, data []byte, maxLines, maxWidth int) []string {
+	var blocks []string
+	var block []string
+
+	flush := func() {
+		for len(block) > 0 {
+			n := min(len(block), maxLines)
+			if text, ok := formatBlock(block[:n], maxWidth); ok {
+				blocks = append(blocks, text)
+			}
+			block = block[n:]
+		}
+	}
+
+	lines := readLines(data)
+	for i, line := range stripComments(lang, lines) {
+		if strings.TrimSpace(line) == "" {
+			if strings.TrimSpace(lines[i]) == "" {
+				flush() // only blank lines separate blocks, not removed comments
+			}
+			continue
+		}
+		block = append(block, strings.ReplaceAll(line, "\t", "    "))
+	}
+	flush()
+
+	return blocks
+}
+
+func formatBlock(lines []string, maxWidth int) (string, bool) {
+	dedent := -1
+	trivial := true
+	for _, line := range lines {
+		if n := len(indentation(line)); dedent < 0 || n < dedent {
+			dedent = n
+		}
+		trivial = trivial && isTrivial(line)
+	}
+	if trivial {
+		return "", false
+	}
+
+	dedented := make([]string, len(lines))
+	for i, line := range lines {
+		dedented[i] = line[dedent:]
+		if utf8.RuneCountInString(dedented[i]) > maxWidth {
+			return "", false
+		}
+	}
+
+	return strings.Join(dedented, "\n"), true
+}
+
+// indentation returns the leading spaces of s.
+func indentation(s string) string {
+	return s[:len(s)-len(strings.TrimLeft(s, " "))]
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		path     string
+		code     string
+		stripped string
+	}{
+		{"main.go", "x := 1 // one", "x := 1"},
+		{"main.go", `s := "// not a comment" // but this is`, `s := "// not a comment"`},
+		{"main.go", "r := `/* raw */` /* block */ + 1", "r := `/* raw */`  + 1"},
+		{"main.go", "a /* spans\nlines */ b\nc", "a\n b\nc"},
+		{"main.go", `q := "\"//" // escaped`, `q := "\"//"`},
+		{"main.c", "char c = '\"'; // quote", "char c = '\"';"},
+		{"main.py", "x = '#' # hash", "x = '#'"},
+		{"main.py", "def f():\n    \"\"\"Docs\n    more.\"\"\"\n    return 1", "def f():\n\n\n    return 1"},
+		{"main.py", `s = """text""" # str`, `s = """text"""`},
+		{"main.py", "f('''#''') # call", "f('''#''')"},
+		{"run.sh", "echo $# # arguments", "echo $#"},
+		{"run.sh", "echo ${#x};# length", "echo ${#x};"},
+		{"run.sh", "# comment\nurl=a#b", "\nurl=a#b"},
+		{"run.pl", "my $n = $#list;", "my $n = $#list;"},
+		{"Makefile", "all: build # default", "all: build"},
+		{"init.lua", "x = 1 --[[ long\ncomment ]] y = 2", "x = 1\n y = 2"},
+		{"init.lua", "s = '--' -- dashes", "s = '--'"},
+		{"q.sql", "SELECT '--' -- literal", "SELECT '--'"},
+		{"Main.hs", "main = {- nested? -} print 1", "main =  print 1"},
+		{"page.html", "<p>a</p><!-- note\n--><b>", "<p>a</p>\n<b>"},
+		{"app.rb", "=begin\ndocs\n=end\nputs 1", "\n\n\nputs 1"},
+	}
+
+	for _, test := range tests {
+		lang, ok := languageFor(test.path)
+		assert.True(t, ok, test.path)
+		stripped := stripComments(lang, strings.Split(test.code, "\n"))
+		assert.Equal(t, test.stripped, strings.Join(stripped, "\n"), test.code)
+	}
+}
+
+func TestCodeLines(t *testing.T) {
+	lang, _ := languageFor("main.go")
+	code := "func main() {\n\t// greet\n\tfmt.Println(1)\n\tfmt.Println(1)\n\tfmt.Println(\"a very long line\")\n}\n"
+	assert.Equal(t, []string{"func main() {", "fmt.Println(1)"}, codeLines(lang, []byte(code), 20))
+	assert.Equal(t, []string{`s := "äöüß"`}, codeLines(lang, []byte(`s := "äöüß"`), 11))
+}
+
+func TestIsGenerated(t *testing.T) {
+	assert.True(t, isGenerated("api.pb.go", nil))
+	assert.True(t, isGenerated("app.min.js", nil))
+	assert.True(t, isGenerated("gen.go", []byte("// Code generated by stringer. DO NOT EDIT.\npackage main\n")))
+	assert.False(t, isGenerated("main.go", []byte("package main\n\n\n\n\n\n// Code generated\n")))
+	assert.False(t, isGenerated("main.go", []byte("package main\n")))
+}
+
+func TestCodeTree(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go":           "package main\n\nfunc main() {}\n",
+		"notes.txt":         "package notes\n",
+		"vendor/lib/lib.go": "package lib\n",
+		".git/hook.sh":      "echo hook\n",
+		".hidden.go":        "package hidden\n",
+		"blob.go":           "package blob\x00\n",
+		"gen.go":            "// Code generated by hand. DO NOT EDIT.\npackage gen\n",
+		"big.go":            "package big\n" + strings.Repeat("// filler\n", maxCodeFileSize/10),
+	} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	}
+
+	messages := readCodeTree(dir, dir)
+	assert.Equal(t, 1, len(messages))
+	state, _ := Init([]string{"gotypist", "-c", "-curriculum", "off"}, map[string]string{})
+	state, _ = reduce(state, messages[0], time.Now())
+	assert.Equal(t, []string{"package main", "func main() {}"}, state.Lines)
+}
+
+func TestCodeBlocks(t *testing.T) {
+	lang, _ := languageFor("main.go")
+	code := "// Package main.\npackage main\n\nfunc main() {\n\tfmt.Println(1) // one\n}\n"
+	assert.Equal(t, []string{"package main", "func main() {\n    fmt.Println(1)\n}"}, codeBlocks(lang, []byte(code), 10, 20))
+	assert.Equal(t, []string{"package main", "func main() {", "fmt.Println(1)"}, codeBlocks(lang, []byte(code), 1, 20))
+	assert.Equal(t, []string{"package main"}, codeBlocks(lang, []byte(code), 10, 17))
+}
+
+func TestTypeBlock(t *testing.T) {
+	source := Datasource{Data: []byte("func f() {\n\tg()\n}\n"), Source: "/f.go"}
+	block := "func f() {\n    g()\n}"
+	for _, indent := range []bool{false, true} {
+		args := []string{"gotypist", "-b", "-curriculum", "off"}
+		if indent {
+			args = append(args, "-indent")
+		}
+		state, _ := Init(args, map[string]string{})
+		state, _ = reduce(state, source, time.Now())
+		assert.Equal(t, block, state.Phrase.Text)
+
+		for _, r := range "func f() {" {
+			state, _ = reduce(state, termbox.Event{Ch: r}, time.Now())
+		}
+		state, _ = reduce(state, termbox.Event{Key: termbox.KeyEnter}, time.Now())
+		if indent {
+			assert.Equal(t, "func f() {\n", state.Phrase.Input)
+			for i := 0; i < 4; i++ {
+				state, _ = reduce(state, termbox.Event{Key: termbox.KeySpace}, time.Now())
+			}
+		}
+		assert.Equal(t, "func f() {\n    ", state.Phrase.Input)
+		assert.Equal(t, 'g', state.Phrase.expected())
+
+		for _, r := range "g()" {
+			state, _ = reduce(state, termbox.Event{Ch: r}, time.Now())
+		}
+		state, _ = reduce(state, termbox.Event{Key: termbox.KeyEnter}, time.Now())
+		state, _ = reduce(state, termbox.Event{Ch: '}'}, time.Now())
+		assert.Equal(t, block, state.Phrase.Input)
+		assert.Empty(t, state.Phrase.CurrentRound().Typos)
+
+		mode := state.Phrase.Mode
+		state, _ = reduce(state, termbox.Event{Key: termbox.KeyEnter}, time.Now())
+		assert.NotEqual(t, mode, state.Phrase.Mode)
+		assert.Equal(t, "", state.Phrase.Input)
+	}
+}
+
+func TestCursorPosition(t *testing.T) {
+	line, col := cursorPosition("ab\ncd", 4)
+	assert.Equal(t, []int{1, 1}, []int{line, col})
+	line, col = cursorPosition("äb\n", 2)
+	assert.Equal(t, []int{0, 1}, []int{line, col})
+	line, col = cursorPosition("ab\n", 3)
+	assert.Equal(t, []int{1, 0}, []int{line, col})
+}
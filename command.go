@@ -2,17 +2,40 @@
 package main
 
 import (
-	_ "embed"
+	"bufio"
+	"bytes"
+	"embed"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/nsf/termbox-go"
 )
 
-//go:embed dictionary
-var builtinDictionary []byte
+//go:embed dictionaries
+var builtinDictionaries embed.FS
+
+const builtinDictionariesDir = "dictionaries"
+
+//go:embed curriculum
+var builtinCurriculum []byte
+
+//go:embed lessons
+var builtinLessons embed.FS
+
+const builtinLessonsDir = "lessons"
+
+//go:embed sentences
+var builtinSentences embed.FS
+
+const builtinSentencesDir = "sentences"
 
 type Message interface{}
 
@@ -41,12 +64,81 @@ type AppendFile struct {
 	Error    func(error) Message
 }
 
+type ReadCodeTree struct {
+	Path   string
+	Source string
+}
+
+type ReadGitHistory struct {
+	Path   string
+	Source string
+	Diffs  bool
+	Count  int
+}
+
+// ReadShellHistory reads the history files of bash, zsh and fish, any of
+// which may be missing.
+type ReadShellHistory struct {
+	Bash string
+	Zsh  string
+	Fish string
+}
+
+// StartExec starts the phrase provider program given with -exec.
+type StartExec struct {
+	Command string
+}
+
+// RequestPhrase asks the -exec program for the next phrase.
+type RequestPhrase struct{}
+
+// ReportResult sends a finished round to the -exec program.
+type ReportResult struct {
+	Data []byte
+}
+
+// LoadPlaylistEntry runs Load and tags its messages with the index of the
+// playlist entry they belong to.
+type LoadPlaylistEntry struct {
+	Index int
+	Load  Command
+}
+
+type WriteFile struct {
+	Filename string
+	Data     []byte
+	Success  func() Message
+	Error    func(error) Message
+}
+
 type Exit struct {
 	Status         int
 	GoodbyeMessage string
 }
 
-type LoadBuiltinDictionary struct{}
+type LoadBuiltinDictionary struct {
+	Lang string
+}
+
+type QueryTerminalSize struct{}
+
+type LoadBuiltinCurriculum struct{}
+
+// LoadBuiltinDrill selects the formats of the built-in number and symbol
+// drills.
+type LoadBuiltinDrill struct {
+	Formats []string
+}
+
+type LoadBuiltinSentences struct {
+	Lang string
+}
+
+type LoadBuiltinLesson struct {
+	Name string
+}
+
+type ListBuiltinLessons struct{}
 
 func PassError(err error) Message {
 	return err
@@ -58,6 +150,22 @@ func RunCommand(cmd Command) []Message {
 		return readFile(c.Filename, c.Success, c.Error)
 	case AppendFile:
 		return appendFile(c.Filename, c.Data, c.Success, c.Error)
+	case ReadCodeTree:
+		return readCodeTree(c.Path, c.Source)
+	case ReadGitHistory:
+		return readGitHistory(c.Path, c.Source, c.Diffs, c.Count)
+	case ReadShellHistory:
+		return readShellHistory(c.Bash, c.Zsh, c.Fish)
+	case StartExec:
+		return startExec(c.Command)
+	case RequestPhrase:
+		return requestPhrase()
+	case ReportResult:
+		return reportResult(c.Data)
+	case LoadPlaylistEntry:
+		return loadPlaylistEntry(c.Index, c.Load)
+	case WriteFile:
+		return writeFile(c.Filename, c.Data, c.Success, c.Error)
 	case Interrupt:
 		return interrupt(c.Delay)
 	case PeriodicInterrupt:
@@ -65,7 +173,19 @@ func RunCommand(cmd Command) []Message {
 	case Exit:
 		return exit(c.Status, c.GoodbyeMessage)
 	case LoadBuiltinDictionary:
-		return loadBuiltinDictionary()
+		return loadBuiltinDictionary(c.Lang)
+	case QueryTerminalSize:
+		return queryTerminalSize()
+	case LoadBuiltinDrill:
+		return loadBuiltinDrill(c.Formats)
+	case LoadBuiltinSentences:
+		return loadBuiltinSentences(c.Lang)
+	case LoadBuiltinCurriculum:
+		return loadBuiltinCurriculum()
+	case LoadBuiltinLesson:
+		return loadBuiltinLesson(c.Name)
+	case ListBuiltinLessons:
+		return listBuiltinLessons()
 	}
 
 	exit(1, fmt.Sprintf("Cannot handle command of type %T", cmd))
@@ -98,6 +218,21 @@ func appendFile(filename string, data []byte, success func() Message, error func
 	return []Message{success()}
 }
 
+func writeFile(filename string, data []byte, success func() Message, error func(error) Message) []Message {
+	if err := ioutil.WriteFile(filename, data, 0600); err != nil {
+		if error != nil {
+			return []Message{error(err)}
+		}
+		return noMessages
+	}
+
+	if success == nil {
+		return noMessages
+	}
+
+	return []Message{success()}
+}
+
 func readFile(filename string, success func([]byte) Message, errorFunc func(error) Message) []Message {
 	var (
 		content []byte
@@ -119,8 +254,220 @@ func readFile(filename string, success func([]byte) Message, errorFunc func(erro
 	return []Message{success(content)}
 }
 
-func loadBuiltinDictionary() []Message {
-	return []Message{Datasource{Data: builtinDictionary}}
+// maxCodeFileSize is the size above which files in code trees are ignored.
+const maxCodeFileSize = 1 << 20
+
+func readCodeTree(root, source string) []Message {
+	info, err := os.Stat(root)
+	if err != nil {
+		return []Message{err}
+	}
+
+	if !info.IsDir() {
+		return readFile(root, func(data []byte) Message {
+			return Datasource{Data: data, Source: source}
+		}, PassError)
+	}
+
+	var files []CodeFile
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && skipDir(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := languageFor(path); !ok || isHidden(info.Name()) ||
+			!info.Mode().IsRegular() || info.Size() > maxCodeFileSize {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, CodeFile{Path: path, Data: data})
+		return nil
+	})
+	if err != nil {
+		return []Message{err}
+	}
+
+	return []Message{CodeTree{Source: source, Files: files}}
+}
+
+func readGitHistory(path, source string, diffs bool, count int) []Message {
+	args := []string{"-C", path, "-c", "log.showSignature=false", "log", "--no-color", "-n", strconv.Itoa(count)}
+	if diffs {
+		args = append(args, "--format=", "-p", "--no-ext-diff", "--no-renames")
+	} else {
+		args = append(args, "--format=%s")
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return []Message{fmt.Errorf("git: %v %s", err, bytes.TrimSpace(stderr.Bytes()))}
+	}
+
+	return []Message{GitHistory{Source: source, Diffs: diffs, Data: out}}
+}
+
+func readShellHistory(bash, zsh, fish string) []Message {
+	var history ShellHistory
+	for _, file := range []struct {
+		name string
+		data *[]byte
+	}{{bash, &history.Bash}, {zsh, &history.Zsh}, {fish, &history.Fish}} {
+		data, err := ioutil.ReadFile(file.name)
+		if err != nil && !os.IsNotExist(err) {
+			return []Message{err}
+		}
+		*file.data = data
+	}
+
+	return []Message{history}
+}
+
+func loadPlaylistEntry(index int, load Command) []Message {
+	var messages []Message
+	for _, message := range RunCommand(load) {
+		if _, ok := message.(error); !ok {
+			message = PlaylistData{Index: index, Message: message}
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// coprocess is the program started with -exec, running as long as gotypist.
+var coprocess struct {
+	stdin  io.Writer
+	stdout *bufio.Reader
+}
+
+func startExec(command string) []Message {
+	cmd := exec.Command("sh", "-c", command)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return []Message{err}
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return []Message{err}
+	}
+	if err := cmd.Start(); err != nil {
+		return []Message{fmt.Errorf("exec: %v", err)}
+	}
+
+	coprocess.stdin = stdin
+	coprocess.stdout = bufio.NewReader(stdout)
+	return noMessages
+}
+
+// execTimeout is how long the -exec program may take to send a phrase.
+var execTimeout = 10 * time.Second
+
+func requestPhrase() []Message {
+	if _, err := coprocess.stdin.Write(execPhraseRequest()); err != nil {
+		return []Message{fmt.Errorf("exec: %v", err)}
+	}
+
+	type reply struct {
+		line string
+		err  error
+	}
+	replies := make(chan reply, 1)
+	go func() {
+		line, err := coprocess.stdout.ReadString('\n')
+		replies <- reply{line, err}
+	}()
+
+	select {
+	case r := <-replies:
+		if r.err == io.EOF && r.line == "" {
+			return []Message{errors.New("exec: the program exited")}
+		} else if r.err != nil && r.err != io.EOF {
+			return []Message{fmt.Errorf("exec: %v", r.err)}
+		}
+		return []Message{ExecPhrase{Text: strings.TrimRight(r.line, "\r\n")}}
+	case <-time.After(execTimeout):
+		return []Message{fmt.Errorf("exec: the program sent no phrase within %v", execTimeout)}
+	}
+}
+
+func reportResult(data []byte) []Message {
+	if _, err := coprocess.stdin.Write(data); err != nil {
+		return []Message{fmt.Errorf("exec: %v", err)}
+	}
+	return noMessages
+}
+
+func loadBuiltinDictionary(lang string) []Message {
+	data, err := builtinDictionaries.ReadFile(builtinDictionariesDir + "/" + lang)
+	if err != nil {
+		return []Message{err}
+	}
+
+	return []Message{Datasource{Data: data, Source: "lang:" + lang}}
+}
+
+func loadBuiltinDrill(formats []string) []Message {
+	return []Message{DrillData{Formats: formats}}
+}
+
+func loadBuiltinSentences(lang string) []Message {
+	data, err := builtinSentences.ReadFile(builtinSentencesDir + "/" + lang)
+	if err != nil {
+		return []Message{fmt.Errorf("no sentence templates for language %s", lang)}
+	}
+
+	return []Message{SentenceData{Data: data, Source: "sentences:" + lang}}
+}
+
+func loadBuiltinCurriculum() []Message {
+	return []Message{CurriculumData{Data: builtinCurriculum}}
+}
+
+func loadBuiltinLesson(name string) []Message {
+	data, err := builtinLessons.ReadFile(builtinLessonsDir + "/" + name)
+	if err != nil {
+		return []Message{fmt.Errorf("unknown lesson %q, see -list-lessons", name)}
+	}
+
+	return []Message{LessonData{Name: name, Data: data}}
+}
+
+func listBuiltinLessons() []Message {
+	entries, err := builtinLessons.ReadDir(builtinLessonsDir)
+	if err != nil {
+		return []Message{err}
+	}
+
+	var lessons []Lesson
+	for _, entry := range entries {
+		data, err := builtinLessons.ReadFile(builtinLessonsDir + "/" + entry.Name())
+		if err != nil {
+			return []Message{err}
+		}
+		lesson, err := parseLesson(entry.Name(), data)
+		if err != nil {
+			return []Message{err}
+		}
+		lessons = append(lessons, lesson)
+	}
+
+	return []Message{LessonList{Lessons: lessons}}
+}
+
+func queryTerminalSize() []Message {
+	w, h := termbox.Size()
+	return []Message{TerminalSize{Width: w, Height: h}}
 }
 
 func periodicInterrupt(d time.Duration) []Message {
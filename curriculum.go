@@ -0,0 +1,110 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// allKeys is the special key set that unlocks every key.
+const allKeys = "*"
+
+// Stage unlocks a set of keys once a given level is reached.
+type Stage struct {
+	Level int
+	Keys  string
+}
+
+// Curriculum is a list of stages, sorted by level.
+//
+// A curriculum file has one stage per line, a level followed by the keys it
+// unlocks:
+//
+//	# level keys
+//	0 asdfghjkl;
+//	2 eiru
+//	9 *
+//
+// Letters unlock their upper case variants as well, and "*" unlocks
+// everything.
+type Curriculum []Stage
+
+func parseCurriculum(data []byte) (Curriculum, error) {
+	var c Curriculum
+
+	for i, line := range readLines(data) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("curriculum, line %d: expected \"LEVEL KEYS\"", i+1)
+		}
+
+		level, err := strconv.Atoi(fields[0])
+		if err != nil || level < 0 {
+			return nil, fmt.Errorf("curriculum, line %d: bad level %q", i+1, fields[0])
+		}
+
+		c = append(c, Stage{Level: level, Keys: fields[1]})
+	}
+
+	if len(c) == 0 {
+		return nil, fmt.Errorf("curriculum is empty")
+	}
+
+	sort.SliceStable(c, func(i, j int) bool { return c[i].Level < c[j].Level })
+	return c, nil
+}
+
+// Unlocked returns the number of stages unlocked at given level. The first
+// stage is always unlocked.
+func (c Curriculum) Unlocked(level int) int {
+	n := 1
+	for n < len(c) && c[n].Level <= level {
+		n++
+	}
+	return n
+}
+
+// Keys returns the keys unlocked by the first n stages.
+func (c Curriculum) Keys(n int) string {
+	var keys strings.Builder
+	for _, stage := range c[:n] {
+		if stage.Keys == allKeys {
+			return allKeys
+		}
+		keys.WriteString(stage.Keys)
+	}
+	return keys.String()
+}
+
+func typeable(word string, keys string) bool {
+	if keys == allKeys {
+		return true
+	}
+
+	for _, r := range word {
+		if !strings.ContainsRune(keys, r) && !strings.ContainsRune(keys, unicode.ToLower(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+func filterTypeable(words []string, keys string) []string {
+	filtered := make([]string, 0)
+
+	for _, word := range words {
+		if typeable(word, keys) {
+			filtered = append(filtered, word)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testCurriculum = `# level keys
+3 eiru
+0 asdf
+9 *
+`
+
+func TestParseCurriculum(t *testing.T) {
+	c, err := parseCurriculum([]byte(testCurriculum))
+	assert.NoError(t, err)
+	assert.Equal(t, Curriculum{{0, "asdf"}, {3, "eiru"}, {9, "*"}}, c)
+
+	_, err = parseCurriculum(builtinCurriculum)
+	assert.NoError(t, err)
+
+	for data, message := range map[string]string{
+		"":             "curriculum is empty",
+		"# only\n":     "curriculum is empty",
+		"0 asdf jkl\n": `curriculum, line 1: expected "LEVEL KEYS"`,
+		"x asdf\n":     `curriculum, line 1: bad level "x"`,
+		"0 a\n-1 b\n":  `curriculum, line 2: bad level "-1"`,
+	} {
+		_, err := parseCurriculum([]byte(data))
+		assert.EqualError(t, err, message, data)
+	}
+}
+
+func TestUnlockedKeys(t *testing.T) {
+	c, _ := parseCurriculum([]byte(testCurriculum))
+
+	for level, keys := range map[int]string{0: "asdf", 2: "asdf", 3: "asdfeiru", 8: "asdfeiru", 9: allKeys, 20: allKeys} {
+		assert.Equal(t, keys, c.Keys(c.Unlocked(level)), "level %d", level)
+	}
+}
+
+func TestTypeable(t *testing.T) {
+	assert.True(t, typeable("dead", "adef"))
+	assert.True(t, typeable("Dead", "adef"))
+	assert.False(t, typeable("deaf!", "adef"))
+	assert.True(t, typeable("anything", allKeys))
+	assert.Equal(t, []string{"sad", "fad"}, filterTypeable([]string{"sad", "sap", "fad"}, "asdf"))
+}
+
+func TestApplyCurriculum(t *testing.T) {
+	c, _ := parseCurriculum([]byte(testCurriculum))
+	state := *NewState(1, DefaultPhrase)
+	state.Curriculum = c
+	state.Words = []string{"sad", "fad", "ride", "user", "quiz"}
+	state.NumberProb = 0.5
+
+	state, changed := applyCurriculum(state)
+	assert.True(t, changed)
+	assert.Equal(t, "asdf", state.UnlockedKeys)
+	assert.Equal(t, "asdf", state.Session.Keys)
+	for i := 0; i < 20; i++ {
+		state = resetPhrase(state, true)
+		for _, word := range strings.Fields(state.Phrase.Text) {
+			assert.True(t, typeable(word, "asdf"), word)
+		}
+	}
+
+	_, changed = applyCurriculum(state)
+	assert.False(t, changed)
+
+	state.Score = requiredScore(3) + 1
+	state, changed = applyCurriculum(state)
+	assert.True(t, changed)
+	assert.Equal(t, "asdfeiru", state.UnlockedKeys)
+	assert.Equal(t, "asdf", state.Session.Keys)
+	assert.Equal(t, []Unlock{{Phrase: 21, Keys: "asdfeiru"}}, state.Session.Unlocks)
+
+	state.Score = requiredScore(9) + 1
+	state, _ = applyCurriculum(state)
+	assert.Equal(t, allKeys, state.UnlockedKeys)
+	phrases := ""
+	for seed := int64(0); seed < 10; seed++ {
+		_, phrase := state.PhraseGenerator(seed)
+		phrases += phrase
+	}
+	assert.Regexp(t, `\d`, phrases, "numbers are unlocked")
+}
@@ -0,0 +1,175 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Difficulty rates how awkward a phrase is to type. All factors are ratios
+// between 0 and 1, taken over the keys or the pairs of consecutive keys
+// (bigrams) within words.
+type Difficulty struct {
+	// SameFinger is the ratio of bigrams typed with the same finger on
+	// different keys.
+	SameFinger float64
+	// RowJumps is the ratio of bigrams of one hand skipping over a row.
+	RowJumps float64
+	// PinkyLoad is the ratio of keys typed with a pinky.
+	PinkyLoad float64
+	// Alternation is the ratio of bigrams switching hands.
+	Alternation float64
+	// Shift is the ratio of keys needing Shift.
+	Shift float64
+	// Score weighs the factors to a single number between 0 and 100.
+	Score float64
+}
+
+// DifficultyRange limits the difficulty score of phrases, zero means no
+// limit.
+type DifficultyRange struct {
+	Min, Max int
+}
+
+const (
+	leftHand  = LeftPinky | LeftRing | LeftMiddle | LeftIndex | LeftThumb
+	rightHand = RightThumb | RightIndex | RightMiddle | RightRing | RightPinky
+)
+
+// hand returns the hand typing with fingers, or NoFinger if both or none
+// are involved.
+func hand(fingers Finger) Finger {
+	switch {
+	case fingers == NoFinger:
+		return NoFinger
+	case fingers&leftHand == fingers:
+		return leftHand
+	case fingers&rightHand == fingers:
+		return rightHand
+	}
+	return NoFinger
+}
+
+func phraseDifficulty(phrase string, fingers map[rune]Finger, keys map[rune]Key) Difficulty {
+	var d Difficulty
+	strokes, bigrams, handBigrams, rowBigrams := 0, 0, 0, 0
+
+	for _, word := range strings.Fields(phrase) {
+		prev := rune(0)
+		for _, r := range word {
+			finger, ok := fingers[r]
+			if !ok {
+				prev = 0
+				continue
+			}
+			pos, known := keys[r]
+
+			strokes++
+			if finger&(LeftPinky|RightPinky) != 0 {
+				d.PinkyLoad++
+			}
+			if (known && pos.Shift) || (!known && unicode.IsUpper(r)) {
+				d.Shift++
+			}
+
+			if prev != 0 {
+				prevFinger := fingers[prev]
+				bigrams++
+				if prev != r && finger == prevFinger {
+					d.SameFinger++
+				}
+				if hand(finger) != NoFinger && hand(prevFinger) != NoFinger {
+					handBigrams++
+					if hand(finger) != hand(prevFinger) {
+						d.Alternation++
+					} else if prevPos, ok := keys[prev]; ok && known {
+						rowBigrams++
+						if abs(pos.Row-prevPos.Row) >= 2 {
+							d.RowJumps++
+						}
+					}
+				}
+			}
+			prev = r
+		}
+	}
+
+	d.PinkyLoad = ratio(d.PinkyLoad, strokes)
+	d.Shift = ratio(d.Shift, strokes)
+	d.SameFinger = ratio(d.SameFinger, bigrams)
+	d.Alternation = ratio(d.Alternation, handBigrams)
+	d.RowJumps = ratio(d.RowJumps, rowBigrams)
+
+	score := 0.
+	if strokes > 0 {
+		score = 100 * (0.3*d.SameFinger + 0.2*d.RowJumps + 0.15*d.PinkyLoad +
+			0.2*(1-d.Alternation) + 0.15*d.Shift)
+	}
+	d.Score = math.Min(100, score)
+	return d
+}
+
+func ratio(n float64, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return n / float64(total)
+}
+
+func (r DifficultyRange) validate() error {
+	if r.Min < 0 || r.Max < 0 || r.Min > 100 || r.Max > 100 {
+		return errors.New("difficulty must be between 0 and 100")
+	}
+	if r.Max > 0 && r.Min > r.Max {
+		return errors.New("minimum difficulty exceeds maximum")
+	}
+	return nil
+}
+
+func (r DifficultyRange) IsZero() bool {
+	return r == DifficultyRange{}
+}
+
+func (r DifficultyRange) Contains(score float64) bool {
+	n := int(math.Round(score))
+	return n >= r.Min && (r.Max == 0 || n <= r.Max)
+}
+
+// maxDifficultyAttempts limits the number of phrases generated in vain
+// because they are too easy or too hard.
+const maxDifficultyAttempts = 50
+
+// DifficultyFilter generates phrases with generator until one lies within
+// the difficulty range, or gives up and takes the last one.
+func DifficultyFilter(generator PhraseFunc, difficulty func(string) float64, r DifficultyRange) PhraseFunc {
+	return func(seed int64) (int64, string) {
+		next, phrase := generator(seed)
+		for i := 0; i < maxDifficultyAttempts && !r.Contains(difficulty(phrase)); i++ {
+			next, phrase = generator(next)
+		}
+		return next, phrase
+	}
+}
+
+// byDifficulty leaves out the lines outside of the difficulty range and,
+// with ramp, orders them from easy to hard.
+func byDifficulty(lines []string, difficulty func(string) float64, r DifficultyRange, ramp bool) []string {
+	var kept []string
+	scores := map[string]float64{}
+	for _, line := range lines {
+		scores[line] = difficulty(line)
+		if r.Contains(scores[line]) {
+			kept = append(kept, line)
+		}
+	}
+
+	if ramp {
+		sort.SliceStable(kept, func(i, j int) bool {
+			return scores[kept[i]] < scores[kept[j]]
+		})
+	}
+	return kept
+}
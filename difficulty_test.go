@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var qwerty = layouts["qwerty"]
+
+func TestPhraseDifficulty(t *testing.T) {
+	score := func(phrase string) Difficulty {
+		return phraseDifficulty(phrase, qwerty.Fingers(), qwerty.Keys)
+	}
+
+	alternating := score("the")
+	assert.Equal(t, 0., alternating.SameFinger)
+	assert.Equal(t, 0., alternating.PinkyLoad)
+
+	sameFinger := score("cede")
+	assert.Equal(t, 1., sameFinger.SameFinger)
+	assert.Equal(t, 0., sameFinger.Alternation)
+
+	assert.Equal(t, 1., score("Pa").PinkyLoad)
+	assert.Equal(t, 0.5, score("Pa").Shift)
+	assert.Equal(t, 1., score("zr").RowJumps)
+	assert.Equal(t, 0., score("").Score)
+
+	assert.Less(t, score("hand shape").Score, score("ZAQ!@ ced").Score)
+}
+
+func TestDifficultyFilter(t *testing.T) {
+	lines := []string{"cede", "Zap!", "the", "sly"}
+	difficulty := func(phrase string) float64 { return phraseDifficulty(phrase, qwerty.Fingers(), qwerty.Keys).Score }
+
+	ramped := byDifficulty(lines, difficulty, DifficultyRange{}, true)
+	assert.ElementsMatch(t, lines, ramped)
+	for i := 1; i < len(ramped); i++ {
+		assert.LessOrEqual(t, difficulty(ramped[i-1]), difficulty(ramped[i]))
+	}
+
+	easy := DifficultyRange{Max: 1}
+	assert.Equal(t, []string{"the"}, byDifficulty(lines, difficulty, easy, false))
+
+	generator := DifficultyFilter(SequentialLine(lines), difficulty, easy)
+	seed := int64(0)
+	for i := 0; i < 5; i++ {
+		next, phrase := generator(seed)
+		assert.Equal(t, "the", phrase)
+		seed = next
+	}
+}
+
+func TestRampNeedsLines(t *testing.T) {
+	words := Datasource{Data: []byte("sad\nlads\nflask\n"), Source: "/words.txt"}
+	state, _ := Init([]string{"gotypist", "-ramp", "-curriculum", "off"}, map[string]string{})
+	_, commands := reduce(state, words, time.Now())
+	assert.Equal(t, []Command{Exit{GoodbyeMessage: rampNeedsLines}}, commands)
+
+	lesson := LessonData{Name: "test", Data: []byte("---\norder: random\n---\nls -lha\ngit status\n")}
+	state, _ = reduce(state, lesson, time.Now())
+	assert.Equal(t, []string{"git status", "ls -lha"}, state.Lines, "random lessons are ramped")
+}
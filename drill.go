@@ -0,0 +1,169 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// drillFormats generate the kind of strings ops engineers mistype.
+var drillFormats = map[string]func(*rand.Rand) string{
+	"ipv4":    randomIPv4,
+	"ipv6":    randomIPv6,
+	"hash":    randomHash,
+	"date":    randomDate,
+	"time":    randomTime,
+	"phone":   randomPhone,
+	"version": randomVersion,
+	"decimal": randomDecimal,
+	"path":    randomPath,
+}
+
+func drillFormatNames() []string {
+	var names []string
+	for name := range drillFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseDrillFormats reads a comma-separated list of formats, "all" selects
+// every format.
+func parseDrillFormats(list string) ([]string, error) {
+	if list == "all" {
+		return drillFormatNames(), nil
+	}
+
+	var formats []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := drillFormats[name]; !ok {
+			return nil, fmt.Errorf("unknown drill format %q, use one of %s or all",
+				name, strings.Join(drillFormatNames(), ", "))
+		}
+		formats = append(formats, name)
+	}
+	return formats, nil
+}
+
+func pick(rand *rand.Rand, choices ...string) string {
+	return choices[rand.Intn(len(choices))]
+}
+
+func randomIPv4(rand *rand.Rand) string {
+	ip := fmt.Sprintf("%d.%d.%d.%d", 1+rand.Intn(254), rand.Intn(256), rand.Intn(256), 1+rand.Intn(254))
+	switch rand.Intn(4) {
+	case 0:
+		return ip + "/" + pick(rand, "8", "16", "24", "28", "32")
+	case 1:
+		return ip + ":" + pick(rand, "22", "80", "443", "5432", "6379", "8080", "9090")
+	}
+	return ip
+}
+
+func randomIPv6(rand *rand.Rand) string {
+	groups := make([]string, 8)
+	for i := range groups {
+		groups[i] = strconv.FormatInt(rand.Int63n(0x10000), 16)
+	}
+	if rand.Intn(2) == 0 {
+		// compress a run of zeros
+		start := 1 + rand.Intn(4)
+		return strings.Join(groups[:start], ":") + "::" + strings.Join(groups[start+2+rand.Intn(3):], ":")
+	}
+	return strings.Join(groups, ":")
+}
+
+func randomHash(rand *rand.Rand) string {
+	const digits = "0123456789abcdef"
+	n := []int{7, 8, 12, 40, 64}[rand.Intn(5)]
+	hash := make([]byte, n)
+	for i := range hash {
+		hash[i] = digits[rand.Intn(len(digits))]
+	}
+	return string(hash)
+}
+
+func randomInstant(rand *rand.Rand) time.Time {
+	return time.Unix(946684800+rand.Int63n(1262304000), 0).UTC() // 2000 to 2040
+}
+
+func randomDate(rand *rand.Rand) string {
+	return randomInstant(rand).Format(pick(rand,
+		"2006-01-02", "02/01/2006", "01/02/06", "2006-01-02T15:04:05Z", "Jan 2, 2006", "02.01.2006"))
+}
+
+func randomTime(rand *rand.Rand) string {
+	t := randomInstant(rand).Add(time.Duration(rand.Intn(1000)) * time.Millisecond)
+	return t.Format(pick(rand, "15:04", "15:04:05", "3:04 PM", "15:04:05.000", "15:04:05-07:00"))
+}
+
+func randomPhone(rand *rand.Rand) string {
+	digits := func(n int) string {
+		d := make([]byte, n)
+		for i := range d {
+			d[i] = byte('0' + rand.Intn(10))
+		}
+		return string(d)
+	}
+
+	switch rand.Intn(4) {
+	case 0:
+		return "+1 (" + digits(3) + ") " + digits(3) + "-" + digits(4)
+	case 1:
+		return "+44 20 " + digits(4) + " " + digits(4)
+	case 2:
+		return "+49 " + digits(3) + " " + digits(7)
+	}
+	return digits(3) + "-" + digits(4)
+}
+
+func randomVersion(rand *rand.Rand) string {
+	version := fmt.Sprintf("%d.%d.%d", rand.Intn(5), rand.Intn(20), rand.Intn(30))
+	switch rand.Intn(5) {
+	case 0:
+		return "v" + version
+	case 1:
+		return version + "-" + pick(rand, "rc", "beta", "alpha") + "." + strconv.Itoa(1+rand.Intn(5))
+	case 2:
+		return version + "+build." + strconv.Itoa(rand.Intn(1000))
+	}
+	return version
+}
+
+func randomDecimal(rand *rand.Rand) string {
+	value := strconv.FormatFloat(float64(rand.Intn(100000))/float64([]int{10, 100, 1000}[rand.Intn(3)]), 'f', -1, 64)
+	if rand.Intn(5) == 0 {
+		value = "-" + value
+	}
+	return value + pick(rand, " ms", " s", " MB", " GiB", " kg", " km/h", "%", "px", " V", " USD")
+}
+
+var pathSegments = []string{
+	"usr", "local", "bin", "etc", "var", "log", "home", "src", "app", "cmd",
+	"internal", "config", "build", "dist", "tmp", "data", "lib", "scripts",
+}
+
+func randomPath(rand *rand.Rand) string {
+	segments := make([]string, 1+rand.Intn(4))
+	for i := range segments {
+		segments[i] = pathSegments[rand.Intn(len(pathSegments))]
+	}
+	file := pick(rand, "main.go", "config.yaml", "nginx.conf", "app.log", "index.html",
+		"out.tar.gz", "Makefile", ".env", "id_ed25519.pub", "data_2024-01.csv")
+
+	switch rand.Intn(4) {
+	case 0:
+		return "~/" + strings.Join(segments, "/") + "/" + file
+	case 1:
+		return "./" + strings.Join(segments, "/") + "/" + file
+	case 2:
+		return `C:\` + strings.Join(segments, `\`) + `\` + file
+	}
+	return "/" + strings.Join(segments, "/") + "/" + file
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math/rand"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrillFormats(t *testing.T) {
+	patterns := map[string]string{
+		"ipv4":    `^\d{1,3}(\.\d{1,3}){3}(/\d+|:\d+)?$`,
+		"ipv6":    `^[0-9a-f:]+$`,
+		"hash":    `^[0-9a-f]{7,64}$`,
+		"date":    `^[0-9A-Za-z ,./:-]+$`,
+		"time":    `^\d{1,2}:\d{2}`,
+		"phone":   `^[+0-9() -]+$`,
+		"version": `^v?\d+\.\d+\.\d+([-+][a-z.0-9]+)?$`,
+		"decimal": `^-?\d+(\.\d+)?( ?[A-Za-z%/]+)$`,
+		"path":    `^(~/|\./|/|C:\\)`,
+	}
+
+	rand := rand.New(rand.NewSource(1))
+	for name, format := range drillFormats {
+		for i := 0; i < 100; i++ {
+			s := format(rand)
+			assert.Regexp(t, regexp.MustCompile(patterns[name]), s, name)
+			if name == "ipv4" {
+				assert.NotNil(t, net.ParseIP(strings.FieldsFunc(s, func(r rune) bool { return r == '/' || r == ':' })[0]), s)
+			} else if name == "ipv6" {
+				assert.NotNil(t, net.ParseIP(s), s)
+			}
+		}
+	}
+}
+
+func TestParseDrillFormats(t *testing.T) {
+	formats, err := parseDrillFormats("ipv4, hash")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ipv4", "hash"}, formats)
+
+	formats, err = parseDrillFormats("all")
+	assert.Nil(t, err)
+	assert.Len(t, formats, len(drillFormats))
+
+	_, err = parseDrillFormats("ipv4,zip")
+	assert.NotNil(t, err)
+}
+
+func TestDrillPhrase(t *testing.T) {
+	phrase := DrillPhrase([]string{"version"}, 30, 60)
+	_, text := phrase(7)
+	_, again := phrase(7)
+	assert.Equal(t, text, again)
+	assert.GreaterOrEqual(t, len(text), 30)
+	assert.LessOrEqual(t, len(text), 60)
+}
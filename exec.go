@@ -0,0 +1,43 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// A program started with -exec reads one JSON object per line on stdin:
+//
+//	{"type":"phrase"}
+//	{"type":"result","stats":{...},"score":42.5}
+//
+// It answers phrase requests with the phrase on a line of its own. Results
+// are sent for every finished round, with the same stats as logged to
+// ~/.gotypist.stats, and with the score once the mode cycle is complete.
+// Results are not answered.
+type execMessage struct {
+	Type  string          `json:"type"`
+	Stats json.RawMessage `json:"stats,omitempty"`
+	Score *float64        `json:"score,omitempty"`
+}
+
+func formatExecMessage(m execMessage) []byte {
+	data, err := json.Marshal(m)
+	if err != nil {
+		panic(err)
+	}
+	return append(data, '\n')
+}
+
+func execPhraseRequest() []byte {
+	return formatExecMessage(execMessage{Type: "phrase"})
+}
+
+// execResult wraps a line of stats, score is nil for unscored rounds.
+func execResult(stats []byte, score *float64) []byte {
+	return formatExecMessage(execMessage{
+		Type:  "result",
+		Stats: json.RawMessage(bytes.TrimSpace(stats)),
+		Score: score,
+	})
+}
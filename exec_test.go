@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// phraseScript answers phrase requests with numbered phrases and writes
+// all other messages to results.
+const phraseScript = `n=1
+while read -r line; do
+	case "$line" in
+	*'"type":"phrase"'*) echo "phrase number $n"; n=$((n+1)) ;;
+	*) echo "$line" >> "$RESULTS" ;;
+	esac
+done`
+
+func TestExec(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	results := filepath.Join(t.TempDir(), "results")
+	state, commands := Init([]string{"gotypist", "-curriculum", "off",
+		"-exec", "RESULTS=" + results + "\n" + phraseScript}, map[string]string{})
+	state = run(state, commands)
+	assert.Equal(t, "phrase number 1", state.Phrase.Text)
+
+	now := time.Now()
+	for _, r := range state.Phrase.Text {
+		state, _ = reduce(state, termbox.Event{Ch: r}, now)
+	}
+	state, commands = reduce(state, termbox.Event{Key: termbox.KeyEnter}, now.Add(time.Second))
+	state = run(state, commands)
+	assert.Equal(t, "phrase number 1", state.Phrase.Text)
+	assert.Equal(t, ModeSlow, state.Phrase.Mode)
+
+	state, commands = reduce(state, termbox.Event{Key: termbox.KeyCtrlF}, now)
+	state = run(state, commands)
+	assert.Equal(t, "phrase number 2", state.Phrase.Text)
+
+	data, err := ioutil.ReadFile(results)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `{"type":"result","stats":{"text":"phrase number 1"`)
+}
+
+func TestExecTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	defer func(timeout time.Duration) { execTimeout = timeout }(execTimeout)
+	execTimeout = 50 * time.Millisecond
+
+	assert.Empty(t, RunCommand(StartExec{Command: "cat > /dev/null"}))
+	messages := RunCommand(RequestPhrase{})
+	assert.EqualError(t, messages[0].(error), "exec: the program sent no phrase within 50ms")
+}
+
+// run executes commands like the main loop, except for file writes.
+func run(state State, commands []Command) State {
+	for _, command := range commands {
+		switch command.(type) {
+		case StartExec, RequestPhrase, ReportResult:
+			for _, message := range RunCommand(command) {
+				next, more := reduce(state, message, time.Now())
+				state = run(next, more)
+			}
+		}
+	}
+	return state
+}
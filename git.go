@@ -0,0 +1,90 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeader matches the start of a hunk and captures the number of old
+// and new lines in it, if given.
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+\d+(?:,(\d+))? @@`)
+
+// diffAdditions collects the lines added to each file in a unified diff, as
+// printed by git log -p. Lines are counted off the hunk headers so that added
+// lines looking like headers are not taken for them.
+func diffAdditions(diff []byte) []CodeFile {
+	var files []CodeFile
+	var added *bytes.Buffer
+	oldLines, newLines := 0, 0 // left in the current hunk
+
+	for _, line := range readLines(diff) {
+		if oldLines > 0 || newLines > 0 {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				newLines--
+				if added != nil {
+					added.WriteString(line[1:])
+					added.WriteByte('\n')
+					files[len(files)-1].Data = added.Bytes()
+				}
+			case strings.HasPrefix(line, "-"):
+				oldLines--
+			case strings.HasPrefix(line, "\\"):
+				// no newline at end of file
+			default:
+				oldLines--
+				newLines--
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			path := strings.TrimPrefix(line, "+++ ")
+			if path == "/dev/null" {
+				added = nil
+				continue
+			}
+			files = append(files, CodeFile{Path: strings.TrimPrefix(path, "b/")})
+			added = new(bytes.Buffer)
+		case strings.HasPrefix(line, "diff "):
+			added = nil
+		case hunkHeader.MatchString(line):
+			counts := hunkHeader.FindStringSubmatch(line)
+			oldLines, newLines = hunkCount(counts[1]), hunkCount(counts[2])
+		}
+	}
+
+	return files
+}
+
+// hunkCount parses the line count of a hunk header, which is 1 if omitted.
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// gitLines extracts the phrases from git log output, either commit subjects
+// or the lines added in diffs. Both are subject to the rules for lines of
+// code.
+func gitLines(history GitHistory, maxLength int) []string {
+	if !history.Diffs {
+		return codeLines(Language{}, history.Data, maxLength)
+	}
+
+	var lines []string
+	for _, file := range diffAdditions(history.Data) {
+		lang, _ := languageFor(file.Path)
+		if isGenerated(file.Path, file.Data) {
+			continue
+		}
+		lines = append(lines, codeLines(lang, file.Data, maxLength)...)
+	}
+	return lines
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffAdditions(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ package main
++// say hello
++func hello() {}
+-func bye() {}
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+`
+
+	files := diffAdditions([]byte(diff))
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, "main.go", files[0].Path)
+	assert.Equal(t, "// say hello\nfunc hello() {}\n", string(files[0].Data))
+
+	lines := gitLines(GitHistory{Diffs: true, Data: []byte(diff)}, 80)
+	assert.Equal(t, []string{"func hello() {}"}, lines)
+}
+
+func TestDiffAdditionsHunks(t *testing.T) {
+	diff := `diff --git a/calc.c b/calc.c
+--- a/calc.c
++++ b/calc.c
+@@ -1,3 +1,4 @@
+ int f(int i) {
+--- i;
++++ i;
+++i;
+ }
+@@ -9 +10 @@
+-old
+\ No newline at end of file
++new
+\ No newline at end of file
+diff --git a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++diff --git a/x b/x
+`
+
+	files := diffAdditions([]byte(diff))
+	assert.Equal(t, []CodeFile{
+		{Path: "calc.c", Data: []byte("++ i;\n+i;\nnew\n")},
+		{Path: "new.txt", Data: []byte("diff --git a/x b/x\n")},
+	}, files)
+}
+
+func TestReadGitHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "gotypist-git")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+			"GIT_CONFIG_NOSYSTEM=1", "HOME="+dir)
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+
+	git("init", "-q")
+	commit := func(name, content, subject string) {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+		git("add", name)
+		git("commit", "-q", "-m", subject)
+	}
+	commit("a.go", "package a\n", "Add package a")
+	commit("a.go", "package a\n\n// Answer is the answer.\nconst Answer = 42\n", "Define the answer")
+
+	messages := readGitHistory(dir, "git:"+dir, false, 10)
+	assert.Equal(t, 1, len(messages))
+	history := messages[0].(GitHistory)
+	assert.Equal(t, []string{"Define the answer", "Add package a"}, gitLines(history, 80))
+
+	messages = readGitHistory(dir, "git:"+dir, true, 10)
+	history = messages[0].(GitHistory)
+	assert.Equal(t, []string{"const Answer = 42", "package a"}, gitLines(history, 80))
+
+	messages = readGitHistory(filepath.Join(dir, "missing"), "", false, 10)
+	_, isError := messages[0].(error)
+	assert.True(t, isError)
+}
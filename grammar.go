@@ -0,0 +1,113 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Grammar makes up sentences from templates with slots for parts of speech,
+// like "{Det} {adj} {noun} {verb} {det} {noun}.". A capitalised slot is
+// filled with a capitalised word.
+type Grammar struct {
+	Templates []string
+	Words     map[string][]string
+}
+
+var (
+	grammarSlot = regexp.MustCompile(`\{([A-Za-z]+)\}`)
+	indefinite  = regexp.MustCompile(`\b([Aa]) ([AEIOUaeiou])`)
+)
+
+var numberWords = []string{"two", "three", "four", "five", "six", "seven", "eight", "nine", "ten", "twelve"}
+
+// parseGrammar reads templates from lines starting with "=" and word lists
+// from lines like "noun: cat dog". Comments start with "#".
+func parseGrammar(data []byte) (Grammar, error) {
+	g := Grammar{Words: map[string][]string{}}
+
+	for i, line := range readLines(data) {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "="):
+			g.Templates = append(g.Templates, strings.TrimSpace(line[1:]))
+		case strings.Contains(line, ":"):
+			kv := strings.SplitN(line, ":", 2)
+			tag := strings.TrimSpace(kv[0])
+			g.Words[tag] = append(g.Words[tag], strings.Fields(kv[1])...)
+		default:
+			return Grammar{}, fmt.Errorf("grammar line %d: expected template or word list", i+1)
+		}
+	}
+
+	if len(g.Templates) == 0 {
+		return Grammar{}, fmt.Errorf("grammar has no templates")
+	}
+	for _, template := range g.Templates {
+		for _, slot := range grammarSlot.FindAllStringSubmatch(template, -1) {
+			tag := strings.ToLower(slot[1])
+			if tag != "num" && len(g.Words[tag]) == 0 {
+				return Grammar{}, fmt.Errorf("grammar has no words for {%s}", slot[1])
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// Sentence fills a random template. Articles are adjusted to the word that
+// follows and the sentence starts with a capital letter.
+func (g Grammar) Sentence(rand *rand.Rand) string {
+	template := g.Templates[rand.Intn(len(g.Templates))]
+	sentence := grammarSlot.ReplaceAllStringFunc(template, func(slot string) string {
+		tag := slot[1 : len(slot)-1]
+		word := g.word(rand, strings.ToLower(tag))
+		if r, _ := utf8.DecodeRuneInString(tag); unicode.IsUpper(r) {
+			word = capitalize(word)
+		}
+		return word
+	})
+
+	return capitalize(indefinite.ReplaceAllString(sentence, "${1}n $2"))
+}
+
+func (g Grammar) word(rand *rand.Rand, tag string) string {
+	if tag == "num" {
+		if rand.Intn(2) == 0 {
+			return numberWords[rand.Intn(len(numberWords))]
+		}
+		return strconv.Itoa(2 + rand.Intn(98))
+	}
+	words := g.Words[tag]
+	return words[rand.Intn(len(words))]
+}
+
+// Material lists templates and words in the format read by parseGrammar.
+func (g Grammar) Material() []string {
+	var tags []string
+	for tag := range g.Words {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	var material []string
+	for _, template := range g.Templates {
+		material = append(material, "= "+template)
+	}
+	for _, tag := range tags {
+		material = append(material, tag+": "+strings.Join(g.Words[tag], " "))
+	}
+	return material
+}
+
+func capitalize(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[n:]
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"math/rand"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrammar(t *testing.T) {
+	grammar, err := parseGrammar([]byte(`# test
+= {Det} {adj} {noun} {verb} {num} {noun}s.
+det: a
+adj: old
+noun: cat
+verb: saw
+`))
+	assert.Nil(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^An old cat saw (\d+|[a-z]+) cats\.$`), grammar.Sentence(rand.New(rand.NewSource(1))))
+
+	reparsed, err := parseGrammar([]byte(joinLines(grammar.Material())))
+	assert.Nil(t, err)
+	assert.Equal(t, grammar, reparsed)
+
+	_, err = parseGrammar([]byte("= {Det} {noun}\ndet: the\n"))
+	assert.EqualError(t, err, "grammar has no words for {noun}")
+}
+
+func TestSentencePhrase(t *testing.T) {
+	grammar, err := parseGrammar(builtinSentencesData(t, "en"))
+	assert.Nil(t, err)
+
+	phrase := SentencePhrase(grammar, 30, 80)
+	next, text := phrase(42)
+	_, again := phrase(42)
+	assert.Equal(t, text, again)
+	assert.LessOrEqual(t, len(text), 80)
+	assert.Regexp(t, regexp.MustCompile(`^[A-Z0-9].*[.?!]$`), text)
+
+	_, other := phrase(next)
+	assert.NotEqual(t, text, other)
+}
+
+func joinLines(lines []string) string {
+	text := ""
+	for _, line := range lines {
+		text += line + "\n"
+	}
+	return text
+}
+
+func builtinSentencesData(t *testing.T, lang string) []byte {
+	data, err := builtinSentences.ReadFile(builtinSentencesDir + "/" + lang)
+	assert.Nil(t, err)
+	return data
+}
@@ -0,0 +1,125 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	bashTimestamp = regexp.MustCompile(`^#\d+$`)
+	zshExtended   = regexp.MustCompile(`^: \d+:\d+;`)
+)
+
+// secretPatterns match commands that likely contain secrets.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)passw(or)?d|passwd|secret|token|api[_-]?key|credential|private[_-]?key|bearer|authorization`),
+	regexp.MustCompile(`(^|[;&|]\s*)export\s`),
+	regexp.MustCompile(`(^|\s)[A-Z_][A-Z0-9_]*=\S`),
+	regexp.MustCompile(`://[^/\s:@]+:[^/\s@]+@`),
+	regexp.MustCompile(`\s-u\s*\S+:\S+`),
+	regexp.MustCompile(`\bmysql\b.*\s-p\S`),
+	regexp.MustCompile(`[A-Za-z0-9+_=-]{32,}`),
+}
+
+func parseBashHistory(data []byte) []string {
+	var commands []string
+	for _, line := range readLines(data) {
+		if !bashTimestamp.MatchString(line) {
+			commands = append(commands, line)
+		}
+	}
+	return commands
+}
+
+// parseZshHistory reads both the plain and the extended history format
+// (": start:duration;command"), joining multi-line commands.
+func parseZshHistory(data []byte) []string {
+	var commands []string
+	command := ""
+	continued := false
+
+	for _, line := range readLines(unmetafy(data)) {
+		if !continued {
+			command = zshExtended.ReplaceAllString(line, "")
+		} else {
+			command += "\n" + line
+		}
+
+		continued = strings.HasSuffix(line, "\\")
+		if !continued {
+			commands = append(commands, command)
+		}
+	}
+
+	return commands
+}
+
+// unmetafy decodes the special characters in zsh history files, which are
+// stored as 0x83 followed by the character XOR 32.
+func unmetafy(data []byte) []byte {
+	const meta = 0x83
+
+	decoded := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		if data[i] == meta && i+1 < len(data) {
+			i++
+			decoded = append(decoded, data[i]^32)
+		} else {
+			decoded = append(decoded, data[i])
+		}
+	}
+	return decoded
+}
+
+// parseFishHistory reads the "- cmd: ..." entries of a fish history file.
+func parseFishHistory(data []byte) []string {
+	var commands []string
+	unescape := strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+
+	for _, line := range readLines(data) {
+		if strings.HasPrefix(line, "- cmd: ") {
+			commands = append(commands, unescape.Replace(strings.TrimPrefix(line, "- cmd: ")))
+		}
+	}
+	return commands
+}
+
+func looksSecret(command string) bool {
+	for _, pattern := range secretPatterns {
+		if pattern.MatchString(command) {
+			return true
+		}
+	}
+	return false
+}
+
+// historyLines merges shell histories into single-line commands without
+// secrets, most frequently used first.
+func historyLines(history ShellHistory, maxLength int) []string {
+	var commands []string
+	commands = append(commands, parseBashHistory(history.Bash)...)
+	commands = append(commands, parseZshHistory(history.Zsh)...)
+	commands = append(commands, parseFishHistory(history.Fish)...)
+
+	counts := map[string]int{}
+	var unique []string
+	for _, command := range commands {
+		command = strings.TrimSpace(command)
+		if command == "" || strings.Contains(command, "\n") ||
+			len([]rune(command)) > maxLength || isTrivial(command) || looksSecret(command) {
+			continue
+		}
+		if counts[command] == 0 {
+			unique = append(unique, command)
+		}
+		counts[command]++
+	}
+
+	sort.SliceStable(unique, func(i, j int) bool {
+		return counts[unique[i]] > counts[unique[j]]
+	})
+
+	return unique
+}
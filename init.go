@@ -4,6 +4,7 @@ package main
 import (
 	"bytes"
 	"flag"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -11,11 +12,61 @@ import (
 func Init(args []string, env map[string]string) (State, []Command) {
 	state := *NewState(0, DefaultPhrase)
 
+	if len(args) > 2 && args[1] == "layout" && args[2] == "analyze" {
+		return initAnalysis(state, args)
+	}
+	if len(args) > 1 && args[1] == "layout" {
+		if len(args) < 3 || args[2] != "check" {
+			return State{}, []Command{Exit{Status: 1, GoodbyeMessage: "usage: gotypist layout check|analyze [OPTION]..."}}
+		}
+		state.LayoutCheck = true
+		args = append([]string{args[0]}, args[3:]...)
+	}
+
 	commandLine := flag.NewFlagSet(args[0], flag.ContinueOnError)
 	datafile := commandLine.String("f", "", "load word list from `FILE`. \"-\" for stdin.")
-	commandLine.BoolVar(&state.Codelines, "c", false, "treat -f FILE as lines of code")
+	commandLine.BoolVar(&state.Codelines, "c", false, "treat -f FILE as lines of code, FILE may be a directory")
+	commandLine.BoolVar(&state.Blocks, "b", false, "treat -f FILE as code and practice whole blocks, FILE may be a directory")
+	commandLine.BoolVar(&state.TypeIndent, "indent", false, "type the indentation of -b blocks instead of skipping it")
+	commandLine.BoolVar(&state.Prose, "p", false, "treat -f FILE as prose and go through it sentence by sentence")
 	commandLine.Bool("d", false, "demo mode for screenshot")
 	commandLine.Float64Var(&state.NumberProb, "n", 0, "mix in numbers with `PROBABILITY`")
+	lang := commandLine.String("lang", "en", "use the dictionary of language `CODE`, one of "+
+		strings.Join(localeNames(), ", "))
+	layout := commandLine.String("layout", "qwerty", "show finger hints for keyboard layout `NAME`, one of "+
+		strings.Join(layoutNames(), ", ")+", or defined in a file")
+	remap := commandLine.String("remap", "", "practice layout `FROM:TO` while the system uses layout FROM, implies -layout TO")
+	sentences := commandLine.Bool("sentences", false, "practice made-up sentences instead of random words")
+	drill := commandLine.String("drill", "", "drill numbers and symbols in `FORMATS`, comma-separated: "+
+		strings.Join(drillFormatNames(), ", ")+" or all")
+	gitPath := commandLine.String("git", "", "practice commit subjects of the git repository at `PATH`")
+	gitDiffs := commandLine.Bool("git-diffs", false, "practice lines added in -git PATH commits instead of subjects")
+	gitCount := commandLine.Int("git-count", 100, "use the latest `N` commits of -git PATH")
+	history := commandLine.Bool("history", false, "practice the commands of your bash, zsh and fish history, most frequent first")
+	commandLine.BoolVar(&state.HistoryRandom, "history-random", false, "practice -history commands in random order")
+	var mix listFlag
+	commandLine.Var(&mix, "mix", "mix in the phrases of playlist `ENTRY`, may be given several times")
+	playlist := commandLine.String("playlist", "", "mix the phrases of the sources listed in `FILE`")
+	execCommand := commandLine.String("exec", "", "ask the program `CMD` for phrases, see README")
+	lesson := commandLine.String("l", "", "load built-in lesson `NAME`")
+	listLessons := commandLine.Bool("list-lessons", false, "list built-in lessons and exit")
+	curriculum := commandLine.String("curriculum", "builtin", "unlock keys by level as defined in `FILE`, \"builtin\" or \"off\"")
+	commandLine.StringVar(&state.DrillKeys, "keys", "", "drill words typeable with `KEYS` only")
+	typfile := commandLine.String("typ", "", "import drills and speed tests from GNU Typist script `FILE`")
+	typLabel := commandLine.String("typ-label", "", "start -typ FILE at `LABEL`")
+	typConvert := commandLine.Bool("typ-convert", false, "print -typ FILE as a lesson and exit")
+	commandLine.IntVar(&state.Limits.MinPhrase, "min-phrase", 30, "make random phrases at least `N` characters long")
+	commandLine.IntVar(&state.Limits.MaxPhrase, "max-phrase", 0, "make phrases at most `N` characters long (default terminal width)")
+	commandLine.IntVar(&state.Limits.MinWord, "min-word", 1, "only use words with at least `N` characters")
+	commandLine.IntVar(&state.Limits.MaxWord, "max-word", 8, "only use words with at most `N` characters")
+	commandLine.IntVar(&state.Limits.MaxLine, "max-line", 0, "skip lines of code longer than `N` characters (default terminal width)")
+	commandLine.BoolVar(&state.Restart, "restart", false, "start -c or -p FILE from the beginning instead of resuming")
+	commandLine.IntVar(&state.StartLine, "line", 0, "start -c or -p FILE at line `N`, counting the lines to practice (as shown in the top right corner)")
+	commandLine.Int64Var(&state.FixedSeed, "seed", 0, "pick random phrases starting from seed `N` (default random)")
+	commandLine.IntVar(&state.Difficulty.Min, "min-difficulty", 0, "only use phrases with a difficulty of at least `N` (0 to 100, 0 means no limit)")
+	commandLine.IntVar(&state.Difficulty.Max, "max-difficulty", 0, "only use phrases with a difficulty of at most `N` (0 to 100, 0 means no limit)")
+	commandLine.BoolVar(&state.Ramp, "ramp", false, "go through lines of files, lessons, git and shell history from easy to hard")
+	session := commandLine.String("session", "", "replay the phrases of session `CODE`")
 
 	err := commandLine.Parse(args[1:])
 	if err != nil {
@@ -28,29 +79,300 @@ func Init(args []string, env map[string]string) (State, []Command) {
 		return State{}, []Command{Exit{Status: 1, GoodbyeMessage: err.Error()}}
 	}
 
-	commands := []Command{}
+	if *session != "" {
+		replay, err := parseSession(*session)
+		if err != nil {
+			return State{}, []Command{Exit{Status: 1, GoodbyeMessage: err.Error()}}
+		}
+		state = replaySession(state, replay)
+		*curriculum = "off"
+		if replay.Layout != "" {
+			*layout = replay.Layout
+		}
+		if strings.HasPrefix(replay.Source, "lang:") {
+			*lang = strings.TrimPrefix(replay.Source, "lang:")
+		} else if strings.HasPrefix(replay.Source, "lesson:") && *lesson == "" {
+			*lesson = strings.TrimPrefix(replay.Source, "lesson:")
+		} else if strings.HasPrefix(replay.Source, "drill:") {
+			*drill = strings.TrimPrefix(replay.Source, "drill:")
+		} else if strings.HasPrefix(replay.Source, "sentences:") {
+			*lang = strings.TrimPrefix(replay.Source, "sentences:")
+			*sentences = true
+		}
+	}
 
-	if len(commandLine.Args()) > 0 {
-		state.PhraseGenerator = StaticPhrase(strings.Join(commandLine.Args(), " "))
-		state = resetPhrase(state, false)
-	} else if *datafile == "" {
-		commands = append(commands, LoadBuiltinDictionary{})
-	} else {
-		commands = append(commands, ReadFile{
-			Filename: *datafile,
-			Success:  func(data []byte) Message { return Datasource{Data: data} },
-			Error:    PassError,
-		})
+	if err := state.Limits.validate(); err != nil {
+		return State{}, []Command{Exit{Status: 1, GoodbyeMessage: err.Error()}}
+	}
+	if state.DrillKeys != "" && strings.TrimSpace(state.DrillKeys) == "" {
+		return State{}, []Command{Exit{Status: 1, GoodbyeMessage: "-keys needs at least one key besides space"}}
+	}
+	if err := state.Difficulty.validate(); err != nil {
+		return State{}, []Command{Exit{Status: 1, GoodbyeMessage: err.Error()}}
+	}
+
+	locale, ok := locales[*lang]
+	if !ok {
+		return State{}, []Command{Exit{Status: 1, GoodbyeMessage: "unknown language " + *lang}}
+	}
+	state.Locale = locale
+
+	if *remap != "" {
+		from, to, err := parseRemap(*remap)
+		if err != nil {
+			return State{}, []Command{Exit{Status: 1, GoodbyeMessage: err.Error()}}
+		}
+		state.Remap = remapLayout(from, to)
+		*layout = to.Name
+	}
+
+	if builtin, ok := layouts[*layout]; ok {
+		state.Layout = builtin
+		state.FingerMap = locale.Fingers(state.Layout.Fingers())
+	}
+
+	if *listLessons {
+		return State{}, []Command{ListBuiltinLessons{}}
 	}
 
 	home, _ := env["HOME"]
 	state.Statsfile = home + "/.gotypist.stats"
+	state.Positionsfile = home + "/.gotypist.positions"
+	state.Skipsfile = home + "/.gotypist.skips"
+	state.HistoryFiles = shellHistory(env)
+
+	commands := []Command{QueryTerminalSize{}}
 
-	return state, append(commands,
+	if _, ok := layouts[*layout]; !ok {
+		commands = append(commands, ReadFile{
+			Filename: *layout,
+			Success:  func(data []byte) Message { return LayoutData{Name: *layout, Data: data} },
+			Error:    layoutFileError(*layout),
+		})
+	}
+
+	// the score decides which keys the curriculum unlocks, so it is read
+	// before the phrases
+	commands = append(commands,
 		ReadFile{
 			Filename: state.Statsfile,
 			Success:  func(data []byte) Message { return StatsData{Data: data} },
 		},
-		PeriodicInterrupt{250 * time.Millisecond},
-	)
+		ReadFile{
+			Filename: state.Skipsfile,
+			Success:  func(data []byte) Message { return SkipsData{Data: data} },
+		})
+
+	switch {
+	case *curriculum == "off" || state.DrillKeys != "":
+	case *curriculum == "builtin":
+		commands = append(commands, LoadBuiltinCurriculum{})
+	default:
+		commands = append(commands, ReadFile{
+			Filename: *curriculum,
+			Success:  func(data []byte) Message { return CurriculumData{Data: data} },
+			Error:    PassError,
+		})
+	}
+
+	if state.LayoutCheck && (len(commandLine.Args()) > 0 || *execCommand != "") {
+		return State{}, []Command{Exit{Status: 1, GoodbyeMessage: "layout check needs a source other than WORD... or -exec"}}
+	}
+
+	if len(commandLine.Args()) > 0 {
+		state.PhraseGenerator = StaticPhrase(strings.Join(commandLine.Args(), " "))
+		state = resetPhrase(state, false)
+	} else if len(mix) > 0 {
+		entries, err := parsePlaylist([]byte(strings.Join(mix, "\n")))
+		if err != nil {
+			return State{}, []Command{Exit{Status: 1, GoodbyeMessage: err.Error()}}
+		}
+		var loads []Command
+		state, loads = usePlaylist(state, entries, env["PWD"])
+		commands = append(commands, loads...)
+	} else if *playlist != "" {
+		commands = append(commands, ReadFile{
+			Filename: *playlist,
+			Success: func(data []byte) Message {
+				return PlaylistFile{Data: data, Dir: filepath.Dir(absPath(*playlist, env))}
+			},
+			Error: PassError,
+		})
+	} else if *typfile != "" {
+		source := *typfile
+		if source != "-" {
+			source = absPath(source, env)
+		}
+		commands = append(commands, ReadFile{
+			Filename: *typfile,
+			Success: func(data []byte) Message {
+				return TypData{
+					Name:    *typfile,
+					Source:  source,
+					Data:    data,
+					Label:   *typLabel,
+					Convert: *typConvert,
+				}
+			},
+			Error: PassError,
+		})
+	} else if *gitPath != "" {
+		commands = append(commands,
+			readPositions(state.Positionsfile),
+			ReadGitHistory{
+				Path:   *gitPath,
+				Source: "git:" + absPath(*gitPath, env),
+				Diffs:  *gitDiffs,
+				Count:  *gitCount,
+			})
+	} else if *execCommand != "" {
+		state.Exec = true
+		commands = append(commands, StartExec{Command: *execCommand}, RequestPhrase{})
+	} else if *history {
+		commands = append(commands, state.HistoryFiles)
+	} else if *lesson != "" {
+		commands = append(commands, LoadBuiltinLesson{Name: *lesson})
+	} else if *drill != "" {
+		formats, err := parseDrillFormats(*drill)
+		if err != nil {
+			return State{}, []Command{Exit{Status: 1, GoodbyeMessage: err.Error()}}
+		}
+		commands = append(commands, LoadBuiltinDrill{Formats: formats})
+	} else if *sentences {
+		commands = append(commands, LoadBuiltinSentences{Lang: *lang})
+	} else if *datafile == "" {
+		commands = append(commands, LoadBuiltinDictionary{Lang: *lang})
+	} else {
+		source := *datafile
+		if source != "-" {
+			source = absPath(source, env)
+		}
+		commands = append(commands,
+			readPositions(state.Positionsfile),
+			readDatasource(*datafile, source, state.Codelines || state.Blocks))
+	}
+
+	return state, append(commands, PeriodicInterrupt{250 * time.Millisecond})
+}
+
+// initAnalysis sets up "layout analyze", which compares layouts on a corpus
+// instead of starting a session.
+func initAnalysis(state State, args []string) (State, []Command) {
+	commandLine := flag.NewFlagSet(args[0]+" layout analyze", flag.ContinueOnError)
+	var names listFlag
+	commandLine.Var(&names, "layout", "analyze keyboard layout `NAME` or FILE, may be given several times (default all built-in layouts)")
+	corpus := commandLine.String("corpus", "", "analyze the layouts for the text in `FILE`, \"-\" for stdin")
+
+	err := commandLine.Parse(args[3:])
+	if err != nil {
+		if err == flag.ErrHelp {
+			buf := new(bytes.Buffer)
+			commandLine.SetOutput(buf)
+			commandLine.PrintDefaults()
+			return State{}, []Command{Exit{Status: 1, GoodbyeMessage: buf.String()}}
+		}
+		return State{}, []Command{Exit{Status: 1, GoodbyeMessage: err.Error()}}
+	}
+	if *corpus == "" {
+		return State{}, []Command{Exit{Status: 1, GoodbyeMessage: "layout analyze needs a -corpus FILE"}}
+	}
+
+	if len(names) == 0 {
+		names = layoutNames()
+	}
+
+	var commands []Command
+	for _, name := range names {
+		for _, name := range strings.Split(name, ",") {
+			state.Analysis = append(state.Analysis, Layout{Name: name})
+			if builtin, ok := layouts[name]; ok {
+				state.Analysis[len(state.Analysis)-1] = builtin
+				continue
+			}
+			name := name
+			commands = append(commands, ReadFile{
+				Filename: name,
+				Success:  func(data []byte) Message { return LayoutData{Name: name, Data: data} },
+				Error:    layoutFileError(name),
+			})
+		}
+	}
+
+	return state, append(commands, ReadFile{
+		Filename: *corpus,
+		Success:  func(data []byte) Message { return CorpusData{Data: data} },
+		Error:    PassError,
+	})
+}
+
+// replaySession sets up everything that determines the phrases of session,
+// except for the source.
+func replaySession(state State, session Session) State {
+	state.Replay = &session
+	state.FixedSeed = session.Seed
+	state.StartLine = session.Line
+	state.Limits = session.Limits
+	state.NumberProb = session.NumberProb
+	state.Difficulty = session.Difficulty
+	state.Ramp = session.Ramp
+	if session.Keys != allKeys {
+		state.DrillKeys = session.Keys
+	}
+	return state
+}
+
+func readDatasource(filename, source string, code bool) Command {
+	if code && filename != "-" {
+		return ReadCodeTree{Path: filename, Source: source}
+	}
+
+	return ReadFile{
+		Filename: filename,
+		Success:  func(data []byte) Message { return Datasource{Data: data, Source: source} },
+		Error:    PassError,
+	}
+}
+
+func absPath(path string, env map[string]string) string {
+	return resolvePath(path, env["PWD"])
+}
+
+// listFlag collects the values of a flag given several times, or separated
+// by semicolons.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, "; ")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, strings.Split(value, ";")...)
+	return nil
+}
+
+// shellHistory locates the history files of bash, zsh and fish.
+func shellHistory(env map[string]string) ReadShellHistory {
+	home := env["HOME"]
+
+	zdotdir := env["ZDOTDIR"]
+	if zdotdir == "" {
+		zdotdir = home
+	}
+	data := env["XDG_DATA_HOME"]
+	if data == "" {
+		data = filepath.Join(home, ".local", "share")
+	}
+
+	return ReadShellHistory{
+		Bash: filepath.Join(home, ".bash_history"),
+		Zsh:  filepath.Join(zdotdir, ".zsh_history"),
+		Fish: filepath.Join(data, "fish", "fish_history"),
+	}
+}
+
+func readPositions(filename string) Command {
+	return ReadFile{
+		Filename: filename,
+		Success:  func(data []byte) Message { return PositionsData{Data: data} },
+	}
 }
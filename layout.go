@@ -0,0 +1,422 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Key locates a character on the keyboard and tells the finger to type it
+// with. Rows count from the number row (0) down to the bottom row (3), the
+// space bar is row 4. Shift tells whether the character needs Shift.
+type Key struct {
+	Finger   Finger
+	Row, Col int
+	Shift    bool
+}
+
+// Layout places characters on the keys of a keyboard.
+type Layout struct {
+	Name string
+	Keys map[rune]Key
+	// Duplicates are the characters a layout file assigned more than once,
+	// the last assignment counts.
+	Duplicates []rune
+}
+
+var fingerNames = map[string]Finger{
+	"left-pinky":   LeftPinky,
+	"left-ring":    LeftRing,
+	"left-middle":  LeftMiddle,
+	"left-index":   LeftIndex,
+	"left-thumb":   LeftThumb,
+	"right-thumb":  RightThumb,
+	"right-index":  RightIndex,
+	"right-middle": RightMiddle,
+	"right-ring":   RightRing,
+	"right-pinky":  RightPinky,
+}
+
+// layoutRows lists the keys of each row of a layout, unshifted and shifted.
+// Spaces are keys without a character.
+type layoutRows [4][2]string
+
+var layouts = map[string]Layout{
+	"qwerty": rowLayout("qwerty", false, layoutRows{
+		{"`1234567890-=", "~!@#$%^&*()_+"},
+		{"qwertyuiop[]\\", "QWERTYUIOP{}|"},
+		{"asdfghjkl;'", "ASDFGHJKL:\""},
+		{"zxcvbnm,./", "ZXCVBNM<>?"},
+	}),
+	"dvorak": rowLayout("dvorak", false, layoutRows{
+		{"`1234567890[]", "~!@#$%^&*(){}"},
+		{"',.pyfgcrl/=\\", "\"<>PYFGCRL?+|"},
+		{"aoeuidhtns-", "AOEUIDHTNS_"},
+		{";qjkxbmwvz", ":QJKXBMWVZ"},
+	}),
+	"colemak": rowLayout("colemak", false, layoutRows{
+		{"`1234567890-=", "~!@#$%^&*()_+"},
+		{"qwfpgjluy;[]\\", "QWFPGJLUY:{}|"},
+		{"arstdhneio'", "ARSTDHNEIO\""},
+		{"zxcvbkm,./", "ZXCVBKM<>?"},
+	}),
+	"workman": rowLayout("workman", false, layoutRows{
+		{"`1234567890-=", "~!@#$%^&*()_+"},
+		{"qdrwbjfup;[]\\", "QDRWBJFUP:{}|"},
+		{"ashtgyneoi'", "ASHTGYNEOI\""},
+		{"zxmcvkl,./", "ZXMCVKL<>?"},
+	}),
+	"qwertz": rowLayout("qwertz", true, layoutRows{
+		{"^1234567890ß´", "°!\"§$%&/()=?`"},
+		{"qwertzuiopü+", "QWERTZUIOPÜ*"},
+		{"asdfghjklöä#", "ASDFGHJKLÖÄ'"},
+		{"<yxcvbnm,.-", ">YXCVBNM;:_"},
+	}),
+	"azerty": rowLayout("azerty", true, layoutRows{
+		{"²&é\"'(-è_çà)=", " 1234567890°+"},
+		{"azertyuiop^$", "AZERTYUIOP¨£"},
+		{"qsdfghjklmù*", "QSDFGHJKLM%µ"},
+		{"<wxcvbn,;:!", ">WXCVBN?./§"},
+	}),
+}
+
+// rowLayout builds a layout of a row-staggered keyboard where each finger
+// covers its usual columns. ISO keyboards have an extra key left of the
+// bottom row.
+func rowLayout(name string, iso bool, rows layoutRows) Layout {
+	keys := map[rune]Key{' ': {Finger: LeftThumb | RightThumb, Row: 4}}
+
+	for row, layers := range rows {
+		for layer, chars := range layers {
+			col := 0
+			if iso && row == 3 {
+				col = -1
+			}
+			for _, r := range chars {
+				if r != ' ' {
+					keys[r] = Key{Finger: columnFinger(row, col), Row: row, Col: col, Shift: layer == 1}
+				}
+				col++
+			}
+		}
+	}
+
+	return Layout{Name: name, Keys: keys}
+}
+
+// columnFinger returns the finger covering a key in touch typing.
+func columnFinger(row, col int) Finger {
+	columns := []Finger{LeftPinky, LeftRing, LeftMiddle, LeftIndex, LeftIndex,
+		RightIndex, RightIndex, RightMiddle, RightRing}
+	if row == 0 {
+		col-- // the number row is offset by half a key to the left
+	}
+
+	switch {
+	case col < 0:
+		return LeftPinky
+	case col >= len(columns):
+		return RightPinky
+	}
+	return columns[col]
+}
+
+// Fingers returns the finger hints of the layout.
+func (l Layout) Fingers() map[rune]Finger {
+	fingers := make(map[rune]Finger, len(l.Keys))
+	for r, key := range l.Keys {
+		fingers[r] = key.Finger
+	}
+	return fingers
+}
+
+// KeyOf returns the key typing r, or the key of the base letter for
+// letters typed with a dead key.
+func (l Layout) KeyOf(r rune) (Key, bool) {
+	if key, ok := l.Keys[r]; ok {
+		return key, true
+	}
+	key, ok := l.Keys[letterBases[unicode.ToLower(r)]]
+	return key, ok
+}
+
+// ShiftFinger returns the pinky holding Shift while finger types r, which
+// is the one of the other hand, or NoFinger if r needs no Shift.
+func (l Layout) ShiftFinger(r rune, finger Finger) Finger {
+	key, ok := l.Keys[r]
+	if (ok && !key.Shift) || (!ok && !unicode.IsUpper(r)) {
+		return NoFinger
+	}
+
+	switch hand(finger) {
+	case leftHand:
+		return RightPinky
+	case rightHand:
+		return LeftPinky
+	}
+	return NoFinger
+}
+
+// ShiftSlip tells whether actual was typed on the key of expected, but
+// with Shift pressed when it should not have been or the other way round.
+// Shift slips often come from holding Shift with the wrong hand.
+func (l Layout) ShiftSlip(expected, actual rune) bool {
+	e, eok := l.Keys[expected]
+	a, aok := l.Keys[actual]
+	if eok && aok {
+		return e.Row == a.Row && e.Col == a.Col && e.Shift != a.Shift
+	}
+	return expected != actual && unicode.ToLower(expected) == unicode.ToLower(actual)
+}
+
+// Labels returns the character printed on each key, the one typed without
+// Shift if there is any, indexed by the row and column of the key.
+func (l Layout) Labels() map[[2]int]rune {
+	labels := map[[2]int]rune{}
+	for r, key := range l.Keys {
+		position := [2]int{key.Row, key.Col}
+		if _, ok := labels[position]; !ok || !key.Shift {
+			labels[position] = r
+		}
+	}
+	return labels
+}
+
+// remapLayout translates the characters typed with the keys of from to the
+// characters on the same keys of to, so that to can be practiced while the
+// system is set up for from.
+func remapLayout(from, to Layout) map[rune]rune {
+	chars := map[Key]rune{}
+	for r, key := range to.Keys {
+		chars[Key{Row: key.Row, Col: key.Col, Shift: key.Shift}] = r
+	}
+
+	remap := map[rune]rune{}
+	for r, key := range from.Keys {
+		if mapped, ok := chars[Key{Row: key.Row, Col: key.Col, Shift: key.Shift}]; ok && mapped != r {
+			remap[r] = mapped
+		}
+	}
+	return remap
+}
+
+// parseRemap parses FROM:TO, naming two built-in layouts.
+func parseRemap(s string) (Layout, Layout, error) {
+	names := strings.Split(s, ":")
+	if len(names) != 2 {
+		return Layout{}, Layout{}, fmt.Errorf("expected FROM:TO, got %q", s)
+	}
+
+	from, ok := layouts[names[0]]
+	if !ok {
+		return Layout{}, Layout{}, fmt.Errorf("unknown layout %s", names[0])
+	}
+	to, ok := layouts[names[1]]
+	if !ok {
+		return Layout{}, Layout{}, fmt.Errorf("unknown layout %s", names[1])
+	}
+	return from, to, nil
+}
+
+func layoutNames() []string {
+	var names []string
+	for name := range layouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// layoutFileError is the Error of reading the layout file name. A name that
+// is neither built in nor a file is most likely a typo.
+func layoutFileError(name string) func(error) Message {
+	return func(err error) Message {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("unknown layout %s, one of %s, or a layout file", name, strings.Join(layoutNames(), ", "))
+		}
+		return err
+	}
+}
+
+// parseLayout reads a layout file, one character per line followed by the
+// fingers typing it, its row and column, and optionally "shift":
+//
+//	# character finger row column [shift]
+//	q left-pinky 1 0
+//	Q left-pinky 1 0 shift
+//	space left-thumb,right-thumb 4 0
+//
+// Characters are given as themselves, as "space", or as U+XXXX, which is
+// needed for "#".
+func parseLayout(name string, data []byte) (Layout, error) {
+	layout := Layout{Name: name, Keys: map[rune]Key{}}
+
+	for i, line := range readLines(data) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		r, key, err := parseLayoutKey(strings.Fields(line))
+		if err != nil {
+			return Layout{}, fmt.Errorf("layout line %d: %v", i+1, err)
+		}
+		if _, ok := layout.Keys[r]; ok {
+			layout.Duplicates = append(layout.Duplicates, r)
+		}
+		layout.Keys[r] = key
+	}
+
+	if len(layout.Keys) == 0 {
+		return Layout{}, errors.New("layout is empty")
+	}
+	return layout, nil
+}
+
+func parseLayoutKey(fields []string) (rune, Key, error) {
+	if len(fields) < 4 || len(fields) > 5 || (len(fields) == 5 && fields[4] != "shift") {
+		return 0, Key{}, fmt.Errorf("expected character, finger, row, column and optionally shift, got %q",
+			strings.Join(fields, " "))
+	}
+
+	r, err := parseLayoutRune(fields[0])
+	if err != nil {
+		return 0, Key{}, err
+	}
+
+	var key Key
+	for _, name := range strings.Split(fields[1], ",") {
+		finger, ok := fingerNames[name]
+		if !ok {
+			return 0, Key{}, fmt.Errorf("unknown finger %q", name)
+		}
+		key.Finger |= finger
+	}
+
+	if key.Row, err = strconv.Atoi(fields[2]); err != nil {
+		return 0, Key{}, fmt.Errorf("invalid row %q", fields[2])
+	}
+	if key.Col, err = strconv.Atoi(fields[3]); err != nil {
+		return 0, Key{}, fmt.Errorf("invalid column %q", fields[3])
+	}
+	key.Shift = len(fields) == 5
+
+	return r, key, nil
+}
+
+func parseLayoutRune(s string) (rune, error) {
+	if s == "space" {
+		return ' ', nil
+	}
+	if strings.HasPrefix(s, "U+") {
+		n, err := strconv.ParseUint(s[2:], 16, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid character %q", s)
+		}
+		return rune(n), nil
+	}
+
+	runes := []rune(s)
+	if len(runes) != 1 {
+		return 0, fmt.Errorf("expected a single character, got %q", s)
+	}
+	return runes[0], nil
+}
+
+func formatLayoutRune(r rune) string {
+	if r == ' ' {
+		return "space"
+	}
+	if r == '#' || !unicode.IsPrint(r) {
+		return fmt.Sprintf("U+%04X", r)
+	}
+	return string(r)
+}
+
+func fingerName(finger Finger) string {
+	var names []string
+	for _, f := range FingerSequence {
+		for name, named := range fingerNames {
+			if f&finger != 0 && named == f {
+				names = append(names, name)
+			}
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// checkLayout reports the characters of phrases which can't be typed with
+// layout, characters assigned more than once, keys with several
+// characters, and fingers without any keys.
+func checkLayout(layout Layout, locale Locale, phrases []string) string {
+	var report []string
+
+	untypeable := map[rune]int{}
+	var order []rune
+	for _, phrase := range phrases {
+		for _, r := range phrase {
+			_, ok := layout.Keys[r]
+			if !ok && strings.ContainsRune(locale.DeadLetters, unicode.ToLower(r)) {
+				_, ok = layout.Keys[letterBases[unicode.ToLower(r)]]
+			}
+			if ok || r == '\n' || r == '\t' {
+				continue
+			}
+			if untypeable[r] == 0 {
+				order = append(order, r)
+			}
+			untypeable[r]++
+		}
+	}
+	for _, r := range order {
+		times := "times"
+		if untypeable[r] == 1 {
+			times = "time"
+		}
+		report = append(report, fmt.Sprintf("can't type %s (%d %s)", formatLayoutRune(r), untypeable[r], times))
+	}
+
+	for _, r := range layout.Duplicates {
+		report = append(report, fmt.Sprintf("%s is assigned more than once", formatLayoutRune(r)))
+	}
+
+	shared := map[Key][]string{}
+	used := NoFinger
+	for r, key := range layout.Keys {
+		used |= key.Finger
+		if r != ' ' {
+			position := Key{Row: key.Row, Col: key.Col, Shift: key.Shift}
+			shared[position] = append(shared[position], formatLayoutRune(r))
+		}
+	}
+	var positions []string
+	for position, chars := range shared {
+		if len(chars) > 1 {
+			sort.Strings(chars)
+			layer := ""
+			if position.Shift {
+				layer = " with shift"
+			}
+			positions = append(positions, fmt.Sprintf("row %d column %d%s has several characters: %s",
+				position.Row, position.Col, layer, strings.Join(chars, " ")))
+		}
+	}
+	sort.Strings(positions)
+	report = append(report, positions...)
+
+	for _, finger := range FingerSequence {
+		if used&finger == 0 {
+			report = append(report, fingerName(finger)+" has no keys")
+		}
+	}
+
+	if len(report) == 0 {
+		return fmt.Sprintf("layout %s: no problems found", layout.Name)
+	}
+	return fmt.Sprintf("layout %s:\n%s", layout.Name, strings.Join(report, "\n"))
+}
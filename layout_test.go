@@ -0,0 +1,159 @@
+package main
+
+import (
+	"io/fs"
+	"testing"
+	"time"
+	"unicode"
+
+	"github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayouts(t *testing.T) {
+	for name, layout := range layouts {
+		assert.Equal(t, name, layout.Name)
+		for r := 'a'; r <= 'z'; r++ {
+			lower, upper := layout.Keys[r], layout.Keys[unicode.ToUpper(r)]
+			assert.False(t, lower.Shift, "%s %c", name, r)
+			assert.True(t, upper.Shift, "%s %c", name, r)
+			assert.Equal(t, Key{Finger: lower.Finger, Row: lower.Row, Col: lower.Col, Shift: true}, upper, "%s %c", name, r)
+		}
+		for r := '0'; r <= '9'; r++ {
+			assert.Contains(t, layout.Keys, r, name)
+		}
+	}
+
+	fingers := layouts["colemak"].Fingers()
+	for r, finger := range map[rune]Finger{'a': LeftPinky, 'r': LeftRing, 's': LeftMiddle, 't': LeftIndex,
+		'n': RightIndex, 'e': RightMiddle, 'i': RightRing, 'o': RightPinky, ' ': LeftThumb | RightThumb} {
+		assert.Equal(t, finger, fingers[r], "%c", r)
+	}
+
+	qwertz := layouts["qwertz"].Fingers()
+	assert.Equal(t, LeftPinky, qwertz['y'])
+	assert.Equal(t, RightPinky, qwertz['ö'])
+	assert.Equal(t, RightPinky, layouts["qwerty"].Fingers()[';'])
+}
+
+func TestParseLayout(t *testing.T) {
+	layout, err := parseLayout("split", []byte(`# a tiny layout
+a left-pinky 2 0
+A left-pinky 2 0 shift
+U+0023 right-ring 0 3 shift
+space left-thumb,right-thumb 4 0
+b left-index 2 0
+a left-ring 2 1
+d left-middle 2 1
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, Key{Finger: LeftRing, Row: 2, Col: 1}, layout.Keys['a'])
+	assert.Equal(t, Key{Finger: RightRing, Col: 3, Shift: true}, layout.Keys['#'])
+	assert.Equal(t, LeftThumb|RightThumb, layout.Keys[' '].Finger)
+	assert.Equal(t, []rune{'a'}, layout.Duplicates)
+
+	for _, invalid := range []string{"", "ab left-pinky 2 0", "a left-toe 2 0", "a left-pinky x 0", "a left-pinky 2 0 alt", "a left-pinky 2"} {
+		_, err := parseLayout("invalid", []byte(invalid))
+		assert.NotNil(t, err, invalid)
+	}
+
+	report := checkLayout(layout, locales["en"], []string{"a bc", "c"})
+	assert.Contains(t, report, "can't type c (2 times)")
+	assert.Contains(t, report, "a is assigned more than once")
+	assert.Contains(t, report, "row 2 column 1 has several characters: a d")
+	assert.NotContains(t, report, "column 0")
+	assert.Contains(t, report, "right-pinky has no keys")
+	assert.NotContains(t, report, "left-thumb")
+
+	assert.Equal(t, "layout qwerty: no problems found", checkLayout(qwerty, locales["en"], []string{"Hello, world!"}))
+	assert.Equal(t, "layout qwertz: no problems found", checkLayout(layouts["qwertz"], locales["de"], []string{"Grüße"}))
+}
+
+func TestUnknownLayout(t *testing.T) {
+	_, commands := Init([]string{"gotypist", "-layout", "dvorka"}, map[string]string{})
+	for _, command := range commands {
+		if read, ok := command.(ReadFile); ok && read.Filename == "dvorka" {
+			err := read.Error(&fs.PathError{Op: "open", Path: "dvorka", Err: fs.ErrNotExist})
+			assert.EqualError(t, err.(error), "unknown layout dvorka, one of azerty, colemak, dvorak, qwerty, qwertz, workman, or a layout file")
+
+			denied := &fs.PathError{Op: "open", Path: "dvorka", Err: fs.ErrPermission}
+			assert.Equal(t, denied, read.Error(denied))
+			return
+		}
+	}
+	t.Error("the layout file is not read")
+}
+
+func TestLayoutCheck(t *testing.T) {
+	state, _ := Init([]string{"gotypist", "layout", "check", "-layout", "dvorak", "-c", "-f", "main.go"}, map[string]string{})
+	assert.True(t, state.LayoutCheck)
+	assert.Equal(t, "dvorak", state.Layout.Name)
+
+	_, commands := reduce(state, Datasource{Data: []byte("fmt.Println(\"naïve\")\n"), Source: "main.go"}, time.Unix(0, 0))
+	assert.Equal(t, []Command{Exit{GoodbyeMessage: "layout dvorak:\ncan't type ï (1 time)"}}, commands)
+
+	_, commands = Init([]string{"gotypist", "layout", "analyse"}, map[string]string{})
+	assert.IsType(t, Exit{}, commands[0])
+}
+
+func TestLayoutLabels(t *testing.T) {
+	labels := qwerty.Labels()
+	assert.Equal(t, 'q', labels[[2]int{1, 0}])
+	assert.Equal(t, '1', labels[[2]int{0, 1}])
+	assert.Equal(t, ' ', labels[[2]int{4, 0}])
+	assert.Len(t, labels, 13+13+11+10+1)
+
+	key, ok := qwerty.KeyOf('Q')
+	assert.True(t, ok)
+	assert.Equal(t, Key{Finger: LeftPinky, Row: 1, Col: 0, Shift: true}, key)
+
+	key, ok = qwerty.KeyOf('â')
+	assert.True(t, ok)
+	assert.Equal(t, qwerty.Keys['a'], key)
+
+	_, ok = qwerty.KeyOf('€')
+	assert.False(t, ok)
+}
+
+func TestShift(t *testing.T) {
+	fingers := qwerty.Fingers()
+	assert.Equal(t, RightPinky, qwerty.ShiftFinger('A', fingers['A']))
+	assert.Equal(t, LeftPinky, qwerty.ShiftFinger('?', fingers['?']))
+	assert.Equal(t, RightPinky, qwerty.ShiftFinger('%', fingers['%']))
+	assert.Equal(t, NoFinger, qwerty.ShiftFinger('a', fingers['a']))
+	assert.Equal(t, NoFinger, qwerty.ShiftFinger(' ', fingers[' ']))
+	assert.Equal(t, LeftPinky, qwerty.ShiftFinger('Ö', RightPinky))
+
+	assert.True(t, qwerty.ShiftSlip('A', 'a'))
+	assert.True(t, qwerty.ShiftSlip('1', '!'))
+	assert.False(t, qwerty.ShiftSlip('A', 's'))
+	assert.True(t, qwerty.ShiftSlip('Ö', 'ö'))
+
+	state := *NewState(0, StaticPhrase("Hi!"))
+	state, _ = reduceRune(state, 'h', time.Unix(0, 0))
+	state, _ = reduceRune(state, 'H', time.Unix(0, 0))
+	state, _ = reduceRune(state, 'i', time.Unix(0, 0))
+	state, _ = reduceRune(state, '1', time.Unix(0, 0))
+	assert.Equal(t, 2, state.Phrase.CurrentRound().ShiftSlips)
+}
+
+func TestRemap(t *testing.T) {
+	remap := remapLayout(qwerty, layouts["colemak"])
+	for typed, mapped := range map[rune]rune{'e': 'f', 'k': 'e', 'E': 'F', ';': 'o', 'P': ':'} {
+		assert.Equal(t, mapped, remap[typed], "%c", typed)
+	}
+	assert.NotContains(t, remap, '1')
+	assert.NotContains(t, remap, 'a')
+
+	state, _ := Init([]string{"gotypist", "-remap", "qwerty:colemak", "-curriculum", "off", "hello"}, map[string]string{})
+	assert.Equal(t, "colemak", state.Layout.Name)
+	for _, ch := range "hkuu;" {
+		state, _ = reduce(state, termbox.Event{Type: termbox.EventKey, Ch: ch}, time.Unix(0, 0))
+	}
+	assert.Equal(t, "hello", state.Phrase.Input)
+
+	for _, invalid := range []string{"qwerty", "qwerty:foo", "foo:qwerty"} {
+		_, commands := Init([]string{"gotypist", "-remap", invalid}, map[string]string{})
+		assert.IsType(t, Exit{}, commands[0], invalid)
+	}
+}
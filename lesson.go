@@ -0,0 +1,167 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Order determines how the lines of a lesson are presented.
+type Order int
+
+const (
+	OrderSequential Order = iota
+	OrderRandom
+)
+
+var orderNames = []string{"sequential", "random"}
+
+// Lesson is a set of phrases with some metadata in a front matter header.
+//
+// A lesson file looks like this:
+//
+//	---
+//	title: Shell commands
+//	description: Everyday one-liners
+//	modes: fast slow normal
+//	order: sequential
+//	keys: |&$-
+//	---
+//	ls -lha
+//	git status
+//
+// All header fields are optional and the header itself can be omitted, in
+// which case every line is a phrase.
+type Lesson struct {
+	Name        string
+	Title       string
+	Description string
+	Modes       []Mode
+	Order       Order
+	Keys        string
+	Lines       []string
+}
+
+const lessonDelimiter = "---"
+
+func (o Order) String() string {
+	return orderNames[o]
+}
+
+func parseOrder(name string) (Order, bool) {
+	for i, n := range orderNames {
+		if n == name {
+			return Order(i), true
+		}
+	}
+	return OrderSequential, false
+}
+
+// isLesson tells whether data starts with a lesson header.
+func isLesson(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(lessonDelimiter+"\n"))
+}
+
+func parseLesson(name string, data []byte) (Lesson, error) {
+	lesson := Lesson{Name: name, Title: name}
+	lines := readLines(data)
+
+	if !isLesson(data) {
+		lesson.Lines = lines
+		return lesson, nil
+	}
+
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == lessonDelimiter {
+			lesson.Lines = lines[i+1:]
+			return lesson, nil
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pair := strings.SplitN(line, ":", 2)
+		if len(pair) != 2 {
+			return lesson, fmt.Errorf("lesson %s, line %d: expected \"key: value\"", name, i+1)
+		}
+
+		key, value := strings.TrimSpace(pair[0]), strings.TrimSpace(pair[1])
+		switch key {
+		case "title":
+			lesson.Title = value
+		case "description":
+			lesson.Description = value
+		case "keys":
+			lesson.Keys = value
+		case "order":
+			order, ok := parseOrder(value)
+			if !ok {
+				return lesson, fmt.Errorf("lesson %s: unknown order %q", name, value)
+			}
+			lesson.Order = order
+		case "modes":
+			modes, err := parseModes(strings.Fields(value))
+			if err != nil {
+				return lesson, fmt.Errorf("lesson %s: %v", name, err)
+			}
+			lesson.Modes = modes
+		default:
+			return lesson, fmt.Errorf("lesson %s: unknown header field %q", name, key)
+		}
+	}
+
+	return lesson, fmt.Errorf("lesson %s: unterminated header", name)
+}
+
+func formatLesson(lesson Lesson) []byte {
+	buf := new(bytes.Buffer)
+
+	fmt.Fprintln(buf, lessonDelimiter)
+	fmt.Fprintf(buf, "title: %s\n", lesson.Title)
+	if lesson.Description != "" {
+		fmt.Fprintf(buf, "description: %s\n", lesson.Description)
+	}
+	if len(lesson.Modes) > 0 {
+		names := make([]string, len(lesson.Modes))
+		for i, m := range lesson.Modes {
+			names[i] = m.Name()
+		}
+		fmt.Fprintf(buf, "modes: %s\n", strings.Join(names, " "))
+	}
+	fmt.Fprintf(buf, "order: %s\n", lesson.Order)
+	if lesson.Keys != "" {
+		fmt.Fprintf(buf, "keys: %s\n", lesson.Keys)
+	}
+	fmt.Fprintln(buf, lessonDelimiter)
+
+	for _, line := range lesson.Lines {
+		fmt.Fprintln(buf, line)
+	}
+
+	return buf.Bytes()
+}
+
+func formatLessonList(lessons []Lesson) string {
+	sort.Slice(lessons, func(i, j int) bool { return lessons[i].Name < lessons[j].Name })
+
+	width := 0
+	for _, l := range lessons {
+		if len(l.Name) > width {
+			width = len(l.Name)
+		}
+	}
+
+	var lines []string
+	for _, l := range lessons {
+		line := fmt.Sprintf("%-*s  %s", width, l.Name, l.Title)
+		if l.Description != "" {
+			line += " - " + l.Description
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLesson(t *testing.T) {
+	lesson, err := parseLesson("shell", []byte(`---
+title: Shell commands
+# comments and blank lines are ignored
+
+description: Everyday one-liners
+modes: normal slow
+order: random
+keys: |&$-
+---
+ls -lha
+git status
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, Lesson{
+		Name:        "shell",
+		Title:       "Shell commands",
+		Description: "Everyday one-liners",
+		Modes:       []Mode{ModeNormal, ModeSlow},
+		Order:       OrderRandom,
+		Keys:        "|&$-",
+		Lines:       []string{"ls -lha", "git status"},
+	}, lesson)
+
+	parsed, err := parseLesson("shell", formatLesson(lesson))
+	assert.NoError(t, err)
+	assert.Equal(t, lesson, parsed)
+}
+
+func TestParseLessonWithoutHeader(t *testing.T) {
+	lesson, err := parseLesson("plain", []byte("one\ntwo\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", lesson.Title)
+	assert.Equal(t, OrderSequential, lesson.Order)
+	assert.Nil(t, lesson.Modes)
+	assert.Equal(t, []string{"one", "two"}, lesson.Lines)
+}
+
+func TestParseLessonErrors(t *testing.T) {
+	for header, message := range map[string]string{
+		"title Shell":       `lesson bad, line 2: expected "key: value"`,
+		"level: 3":          `lesson bad: unknown header field "level"`,
+		"order: shuffled":   `lesson bad: unknown order "shuffled"`,
+		"modes: fast turbo": `lesson bad: unknown mode "turbo"`,
+		"modes: fast fast":  `lesson bad: duplicate mode "fast"`,
+		"modes:":            `lesson bad: no modes given`,
+	} {
+		_, err := parseLesson("bad", []byte("---\n"+header+"\n---\nline\n"))
+		assert.EqualError(t, err, message, header)
+	}
+
+	_, err := parseLesson("bad", []byte("---\ntitle: Open\n"))
+	assert.EqualError(t, err, "lesson bad: unterminated header")
+}
+
+func TestPartialModeCycle(t *testing.T) {
+	now := time.Unix(0, 0)
+	state, _ := useLesson(*NewState(0, DefaultPhrase), Lesson{
+		Modes: []Mode{ModeNormal},
+		Lines: []string{"one", "two"},
+	}, "", now)
+	assert.Equal(t, ModeNormal, state.Phrase.Mode)
+
+	state.Phrase.Input = state.Phrase.Text
+	state, commands := reduceEnter(state, now)
+	assert.Equal(t, "two", state.Phrase.Text)
+	assert.Equal(t, ModeNormal, state.Phrase.Mode)
+	assert.Equal(t, 0., state.Score)
+	assert.NotContains(t, commands, Interrupt{ScoreHighlightDuration})
+}
+
+func TestLessonFile(t *testing.T) {
+	yaml := Datasource{Data: []byte("---\nname: build\non: push\n"), Source: "/src/ci.yml"}
+
+	state, _ := Init([]string{"gotypist", "-curriculum", "off"}, map[string]string{})
+	_, commands := reduce(state, yaml, time.Now())
+	assert.Equal(t, []Command{Exit{GoodbyeMessage: "lesson ci.yml: unknown header field \"name\""}}, commands)
+
+	state, _ = Init([]string{"gotypist", "-c", "-curriculum", "off"}, map[string]string{})
+	state, _ = reduce(state, yaml, time.Now())
+	assert.Nil(t, state.Lesson)
+	assert.Equal(t, []string{"name: build", "on: push"}, state.Lines)
+}
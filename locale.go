@@ -0,0 +1,138 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Locale describes a natural language with a built-in dictionary.
+type Locale struct {
+	Name string
+	// Letters are the letters used besides a to z.
+	Letters string
+	// Capitals tells whether words may start with a capital letter, as
+	// German nouns do.
+	Capitals bool
+	// Keys are letters with a key of their own on the native keyboard.
+	Keys map[Finger]string
+	// DeadLetters are typed by striking DeadKey (a dead key or AltGr) and
+	// the base letter.
+	DeadLetters string
+	DeadKey     Finger
+}
+
+var locales = map[string]Locale{
+	"en": {
+		Name: "English",
+	},
+	"de": {
+		Name:     "German",
+		Letters:  "äöüß",
+		Capitals: true,
+		Keys:     map[Finger]string{RightPinky: "äöüß"},
+	},
+	"fr": {
+		Name:        "French",
+		Letters:     "àâçéèêëîïôùûü",
+		Keys:        map[Finger]string{LeftRing: "é", RightIndex: "è", RightRing: "ç", RightPinky: "àù"},
+		DeadLetters: "âêëîïôûü",
+		DeadKey:     RightPinky,
+	},
+	"es": {
+		Name:        "Spanish",
+		Letters:     "áéíñóúü",
+		Keys:        map[Finger]string{RightPinky: "ñ"},
+		DeadLetters: "áéíóúü",
+		DeadKey:     RightPinky,
+	},
+	"it": {
+		Name:    "Italian",
+		Letters: "àèéìòù",
+		Keys:    map[Finger]string{RightPinky: "àèéìòù"},
+	},
+	"pt": {
+		Name:        "Portuguese",
+		Letters:     "áâãàçéêíóôõú",
+		Keys:        map[Finger]string{RightPinky: "ç"},
+		DeadLetters: "áâãàéêíóôõú",
+		DeadKey:     RightPinky,
+	},
+	"pl": {
+		Name:        "Polish",
+		Letters:     "ąćęłńóśźż",
+		DeadLetters: "ąćęłńóśźż",
+		DeadKey:     RightThumb, // AltGr
+	},
+}
+
+// letterBases maps letters to the letter they are derived from.
+var letterBases = map[rune]rune{
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'ą': 'a',
+	'ç': 'c', 'ć': 'c',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e', 'ę': 'e',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i',
+	'ł': 'l',
+	'ñ': 'n', 'ń': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o',
+	'ß': 's', 'ś': 's',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u',
+	'ż': 'z',
+	'ź': 'x', // AltGr+X on the Polish programmer layout, since AltGr+Z is ż
+}
+
+func localeNames() []string {
+	var names []string
+	for name := range locales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WordPattern matches the words of a dictionary in this language.
+func (l Locale) WordPattern() string {
+	lower := "[a-z" + l.Letters + "]+"
+	if l.Capitals {
+		return "^[A-Z" + strings.ToUpper(l.Letters) + "]?" + lower + "$"
+	}
+	return "^" + lower + "$"
+}
+
+// WithLetters adds the letters derived from any of keys.
+func (l Locale) WithLetters(keys string) string {
+	if keys == allKeys {
+		return keys
+	}
+
+	var derived strings.Builder
+	for _, r := range l.Letters {
+		if strings.ContainsRune(keys, letterBases[r]) {
+			derived.WriteRune(r)
+		}
+	}
+	return keys + derived.String()
+}
+
+// Fingers extends base with the finger hints for the letters of the
+// language.
+func (l Locale) Fingers(base map[rune]Finger) map[rune]Finger {
+	fingers := make(map[rune]Finger, len(base))
+	for r, f := range base {
+		fingers[r] = f
+	}
+
+	for finger, letters := range l.Keys {
+		for _, r := range letters {
+			fingers[r] = finger
+			fingers[unicode.ToUpper(r)] = finger
+		}
+	}
+	for _, r := range l.DeadLetters {
+		fingers[r] = l.DeadKey | base[letterBases[r]]
+		fingers[unicode.ToUpper(r)] = l.DeadKey | base[unicode.ToUpper(letterBases[r])]
+	}
+
+	return fingers
+}
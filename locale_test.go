@@ -0,0 +1,58 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWordPattern(t *testing.T) {
+	en := regexp.MustCompile(locales["en"].WordPattern())
+	assert.True(t, en.MatchString("word"))
+	assert.False(t, en.MatchString("Word"))
+	assert.False(t, en.MatchString("wörd"))
+
+	de := regexp.MustCompile(locales["de"].WordPattern())
+	assert.True(t, de.MatchString("Größe"))
+	assert.True(t, de.MatchString("übel"))
+	assert.True(t, de.MatchString("Übel"))
+	assert.False(t, de.MatchString("ÜBEL"))
+	assert.False(t, de.MatchString("café"))
+}
+
+func TestWithLetters(t *testing.T) {
+	assert.Equal(t, "aou", locales["en"].WithLetters("aou"))
+	assert.Equal(t, "aouäöü", locales["de"].WithLetters("aou"))
+	assert.Equal(t, "sß", locales["de"].WithLetters("s"))
+	assert.Equal(t, "xź", locales["pl"].WithLetters("x"))
+	assert.Equal(t, "zż", locales["pl"].WithLetters("z"))
+	assert.Equal(t, allKeys, locales["de"].WithLetters(allKeys))
+}
+
+func TestLocaleFingers(t *testing.T) {
+	base := qwerty.Fingers()
+
+	de := locales["de"].Fingers(base)
+	assert.Equal(t, RightPinky, de['ä'])
+	assert.Equal(t, RightPinky, de['Ä'])
+	assert.Equal(t, base['a'], de['a'])
+
+	fr := locales["fr"].Fingers(base)
+	assert.Equal(t, LeftRing, fr['é'])
+	assert.Equal(t, RightPinky|base['a'], fr['â'])
+	assert.Equal(t, RightPinky|base['A'], fr['Â'])
+
+	pl := locales["pl"].Fingers(base)
+	assert.Equal(t, RightThumb|base['x'], pl['ź'])
+	assert.Equal(t, RightThumb|base['z'], pl['ż'])
+	assert.NotEqual(t, pl['ź'], pl['ż'])
+
+	assert.Equal(t, 'x', letterBases['ź'])
+	for _, locale := range locales {
+		for _, r := range locale.Letters {
+			_, ok := letterBases[r]
+			assert.True(t, ok, string(r))
+		}
+	}
+}
@@ -54,6 +54,8 @@ func waitForEvent() []Message {
 	switch ev.Type {
 	case termbox.EventKey:
 		return []Message{ev}
+	case termbox.EventResize:
+		return []Message{TerminalSize{Width: ev.Width, Height: ev.Height}}
 	case termbox.EventError:
 		panic(ev.Err)
 	case termbox.EventInterrupt:
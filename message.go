@@ -1,9 +1,104 @@
 package main
 
 type Datasource struct {
-	Data []byte
+	Data   []byte
+	Source string
 }
 
 type StatsData struct {
 	Data []byte
 }
+
+type LessonData struct {
+	Name string
+	Data []byte
+}
+
+type LessonList struct {
+	Lessons []Lesson
+}
+
+// DrillData are the formats of a number and symbol drill.
+type DrillData struct {
+	Formats []string
+}
+
+// SentenceData is a grammar for made-up sentences.
+type SentenceData struct {
+	Data   []byte
+	Source string
+}
+
+type TypData struct {
+	Name    string
+	Source  string
+	Data    []byte
+	Label   string
+	Convert bool
+}
+
+// LayoutData is a keyboard layout file.
+type LayoutData struct {
+	Name string
+	Data []byte
+}
+
+// CorpusData is the text to analyze layouts with.
+type CorpusData struct {
+	Data []byte
+}
+
+type CurriculumData struct {
+	Data []byte
+}
+
+type TerminalSize struct {
+	Width  int
+	Height int
+}
+
+type PositionsData struct {
+	Data []byte
+}
+
+type SkipsData struct {
+	Data []byte
+}
+
+type CodeFile struct {
+	Path string
+	Data []byte
+}
+
+type CodeTree struct {
+	Source string
+	Files  []CodeFile
+}
+
+type GitHistory struct {
+	Source string
+	Diffs  bool
+	Data   []byte
+}
+
+type ShellHistory struct {
+	Bash []byte
+	Zsh  []byte
+	Fish []byte
+}
+
+// ExecPhrase is a phrase sent by the -exec program.
+type ExecPhrase struct {
+	Text string
+}
+
+type PlaylistFile struct {
+	Data []byte
+	Dir  string
+}
+
+// PlaylistData is the material of playlist entry Index.
+type PlaylistData struct {
+	Index   int
+	Message Message
+}
@@ -1,6 +1,10 @@
 package main
 
-import "github.com/nsf/termbox-go"
+import (
+	"fmt"
+
+	"github.com/nsf/termbox-go"
+)
 
 type Mode int
 
@@ -43,3 +47,61 @@ func (m Mode) Desc() string {
 func (m Mode) Attr() termbox.Attribute {
 	return modeInfo[m].Attr
 }
+
+// ModeCycle is the full fast, slow, normal cycle; only phrases typed in all
+// three modes are scored.
+var ModeCycle = []Mode{ModeFast, ModeSlow, ModeNormal}
+
+func parseModes(names []string) ([]Mode, error) {
+	var modes []Mode
+	seen := map[Mode]bool{}
+
+	for _, name := range names {
+		mode, ok := parseMode(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown mode %q", name)
+		}
+		if seen[mode] {
+			return nil, fmt.Errorf("duplicate mode %q", name)
+		}
+		seen[mode] = true
+		modes = append(modes, mode)
+	}
+
+	if len(modes) == 0 {
+		return nil, fmt.Errorf("no modes given")
+	}
+
+	return modes, nil
+}
+
+func parseMode(name string) (Mode, bool) {
+	for i, info := range modeInfo {
+		if info.Name == name {
+			return Mode(i), true
+		}
+	}
+	return ModeFast, false
+}
+
+// nextMode returns the mode following m in given cycle, if there is any.
+func nextMode(cycle []Mode, m Mode) (Mode, bool) {
+	for i, c := range cycle {
+		if c == m && i+1 < len(cycle) {
+			return cycle[i+1], true
+		}
+	}
+	return m, false
+}
+
+func isFullCycle(cycle []Mode) bool {
+	if len(cycle) != len(ModeCycle) {
+		return false
+	}
+	for i := range cycle {
+		if cycle[i] != ModeCycle[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseModes(t *testing.T) {
+	modes, err := parseModes([]string{"slow", "fast"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Mode{ModeSlow, ModeFast}, modes)
+
+	for _, names := range [][]string{nil, {"fast", "turbo"}, {"slow", "slow"}} {
+		_, err := parseModes(names)
+		assert.Error(t, err, "%v", names)
+	}
+}
+
+func TestModeCycle(t *testing.T) {
+	next, ok := nextMode(ModeCycle, ModeFast)
+	assert.True(t, ok)
+	assert.Equal(t, ModeSlow, next)
+
+	_, ok = nextMode(ModeCycle, ModeNormal)
+	assert.False(t, ok)
+
+	_, ok = nextMode([]Mode{ModeSlow}, ModeFast)
+	assert.False(t, ok)
+
+	assert.True(t, isFullCycle([]Mode{ModeFast, ModeSlow, ModeNormal}))
+	assert.False(t, isFullCycle([]Mode{ModeSlow, ModeFast, ModeNormal}))
+	assert.False(t, isFullCycle([]Mode{ModeFast, ModeSlow}))
+}
@@ -0,0 +1,72 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// randomKeyProb is the probability of picking a key uniformly instead of
+// following the bigram statistics. This makes sure that keys which rarely
+// appear in real words get some practice as well.
+const randomKeyProb = 0.2
+
+// ngramModel generates pseudo-words from a restricted set of keys, following
+// the bigram statistics of a word list.
+type ngramModel struct {
+	keys   []rune
+	starts []rune
+	next   map[rune][]rune
+}
+
+func newNgramModel(words []string, keys string) ngramModel {
+	m := ngramModel{next: map[rune][]rune{}}
+
+	seen := map[rune]bool{}
+	for _, k := range keys {
+		if k != ' ' && !seen[k] {
+			seen[k] = true
+			m.keys = append(m.keys, k)
+		}
+	}
+	sort.Slice(m.keys, func(i, j int) bool { return m.keys[i] < m.keys[j] })
+
+	for _, word := range words {
+		prev := rune(0)
+		for _, r := range strings.ToLower(word) {
+			if !seen[r] {
+				prev = 0
+				continue
+			}
+			if prev == 0 {
+				m.starts = append(m.starts, r)
+			} else {
+				m.next[prev] = append(m.next[prev], r)
+			}
+			prev = r
+		}
+	}
+
+	return m
+}
+
+func (m ngramModel) word(rand *rand.Rand, length int) string {
+	var word []rune
+
+	for i := 0; i < length; i++ {
+		choices := m.starts
+		if i > 0 {
+			choices = m.next[word[i-1]]
+		}
+		if len(choices) == 0 || rand.Float64() < randomKeyProb {
+			choices = m.keys
+		}
+		if len(choices) == 0 {
+			break
+		}
+		word = append(word, choices[rand.Intn(len(choices))])
+	}
+
+	return string(word)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNgramWord(t *testing.T) {
+	model := newNgramModel([]string{"salad", "flask", "Dad"}, "asdfl")
+	rand := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		word := model.word(rand, 5)
+		assert.Len(t, word, 5)
+		assert.True(t, typeable(word, "asdfl"), word)
+	}
+
+	assert.Equal(t, "", newNgramModel([]string{"salad"}, " ").word(rand, 3))
+}
+
+func TestKeyDrillPhrase(t *testing.T) {
+	words := []string{"sad", "lads", "flask", "quiz"}
+	assert.NotPanics(t, func() { KeyDrillPhrase(words, " ", 10, 20, 0)(1) })
+
+	_, phrase := KeyDrillPhrase(words, "asdfkl", 30, 60, 0)(1)
+	for _, word := range strings.Fields(phrase) {
+		assert.True(t, typeable(word, "asdfkl"), word)
+	}
+}
+
+func TestKeysWithoutDigits(t *testing.T) {
+	words := Datasource{Data: []byte("sad\nlads\nflask\nask\n"), Source: "words"}
+
+	state, _ := Init([]string{"gotypist", "-keys", "asdfkl", "-n", "1", "-f", "words"}, map[string]string{})
+	state, _ = reduce(state, words, time.Unix(0, 0))
+	for i := 0; i < 10; i++ {
+		assert.NotRegexp(t, `\d`, state.Phrase.Text)
+		state = resetPhrase(state, true)
+	}
+
+	state, _ = Init([]string{"gotypist", "-keys", "asdfkl0123456789", "-n", "1", "-f", "words"}, map[string]string{})
+	state, _ = reduce(state, words, time.Unix(0, 0))
+	assert.Regexp(t, `^\d+( \d+)*$`, state.Phrase.Text)
+
+	_, commands := Init([]string{"gotypist", "-keys", " "}, map[string]string{})
+	assert.IsType(t, Exit{}, commands[0])
+}
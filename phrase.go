@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // PhraseFunc generates a phrase from a seed and returns the next seed.
@@ -27,25 +28,122 @@ func StaticPhrase(phrase string) PhraseFunc {
 }
 
 // RandomPhrase composes a random phrase with given length from given words.
-func RandomPhrase(words []string, minLength int, numProb float64) PhraseFunc {
+func RandomPhrase(words []string, minLength, maxLength int, numProb float64) PhraseFunc {
 	return func(seed int64) (int64, string) {
 		rand := rand.New(rand.NewSource(seed))
-		var phrase []string
-		l := -1
-		for l < minLength {
-			var w string
+		phrase := composePhrase(minLength, maxLength, func() string {
 			if rand.Float64() < numProb {
-				w = strconv.FormatInt(rand.Int63n(10000), 10)
-			} else {
-				w = words[rand.Int31n(int32(len(words)))]
+				return strconv.FormatInt(rand.Int63n(10000), 10)
 			}
-			phrase = append(phrase, w)
-			l += 1 + len(w)
+			return words[rand.Int31n(int32(len(words)))]
+		})
+		return rand.Int63(), phrase
+	}
+}
+
+// SentencePhrase composes a phrase of whole sentences made up by grammar.
+func SentencePhrase(grammar Grammar, minLength, maxLength int) PhraseFunc {
+	return func(seed int64) (int64, string) {
+		rand := rand.New(rand.NewSource(seed))
+		phrase := composePhrase(minLength, maxLength, func() string {
+			return grammar.Sentence(rand)
+		})
+		return rand.Int63(), phrase
+	}
+}
+
+// DrillPhrase composes a phrase of strings in the given drill formats.
+func DrillPhrase(formats []string, minLength, maxLength int) PhraseFunc {
+	return func(seed int64) (int64, string) {
+		rand := rand.New(rand.NewSource(seed))
+		phrase := composePhrase(minLength, maxLength, func() string {
+			return drillFormats[formats[rand.Intn(len(formats))]](rand)
+		})
+		return rand.Int63(), phrase
+	}
+}
+
+// PlaylistPhrase picks one of generators for each phrase, at random and in
+// proportion to its weight.
+func PlaylistPhrase(generators []PhraseFunc, weights []int) PhraseFunc {
+	total := 0
+	for _, weight := range weights {
+		total += weight
+	}
+
+	return func(seed int64) (int64, string) {
+		rand := rand.New(rand.NewSource(seed))
+		n, i := rand.Intn(total), 0
+		for n >= weights[i] {
+			n -= weights[i]
+			i++
+		}
+		_, phrase := generators[i](rand.Int63())
+		return rand.Int63(), phrase
+	}
+}
+
+// minDrillWords is the number of words below which key drills are padded with
+// generated pseudo-words.
+const minDrillWords = 50
+
+// KeyDrillPhrase composes a random phrase from all words typeable with given
+// keys. If there are too few such words, the phrase is padded with
+// pseudo-words generated from bigrams of all words.
+func KeyDrillPhrase(words []string, keys string, minLength, maxLength int, numProb float64) PhraseFunc {
+	drillWords := filterTypeable(words, keys)
+	model := newNgramModel(words, keys)
+	padProb := 1 - float64(len(drillWords))/minDrillWords
+
+	return func(seed int64) (int64, string) {
+		rand := rand.New(rand.NewSource(seed))
+		phrase := composePhrase(minLength, maxLength, func() string {
+			if rand.Float64() < numProb {
+				return strconv.FormatInt(rand.Int63n(10000), 10)
+			} else if len(drillWords) == 0 || rand.Float64() < padProb {
+				return model.word(rand, 2+rand.Intn(4))
+			}
+			return drillWords[rand.Int31n(int32(len(drillWords)))]
+		})
+		return rand.Int63(), phrase
+	}
+}
+
+// Excluding makes generator pick again instead of returning phrase, up to
+// maxPickAttempts times.
+func Excluding(generator PhraseFunc, phrase string) PhraseFunc {
+	return func(seed int64) (int64, string) {
+		next, p := generator(seed)
+		for i := 0; p == phrase && i < maxPickAttempts; i++ {
+			next, p = generator(next)
 		}
-		return rand.Int63(), strings.Join(phrase, " ")
+		return next, p
 	}
 }
 
+// maxPickAttempts limits the number of words picked in vain because they
+// would make the phrase too long.
+const maxPickAttempts = 20
+
+// composePhrase joins words drawn by pick until the phrase is at least
+// minLength long. Words that would make it longer than maxLength are
+// skipped, unless it is the first word.
+func composePhrase(minLength, maxLength int, pick func() string) string {
+	var phrase []string
+	l := -1
+	for attempts := 0; l < minLength && attempts < maxPickAttempts; {
+		w := pick()
+		n := 1 + utf8.RuneCountInString(w)
+		if l+n > maxLength && len(phrase) > 0 {
+			attempts++
+			continue
+		}
+		phrase = append(phrase, w)
+		l += n
+	}
+	return strings.Join(phrase, " ")
+}
+
 // SequentialLine goes through a sequence of lines.
 func SequentialLine(lines []string) PhraseFunc {
 	return func(seed int64) (int64, string) {
@@ -54,13 +152,23 @@ func SequentialLine(lines []string) PhraseFunc {
 	}
 }
 
-func filterWords(words []string, pattern string, maxLength int) []string {
+// RandomLine picks lines at random.
+func RandomLine(lines []string) PhraseFunc {
+	return func(seed int64) (int64, string) {
+		rand := rand.New(rand.NewSource(seed))
+		line := lines[rand.Int31n(int32(len(lines)))]
+		return rand.Int63(), line
+	}
+}
+
+func filterWords(words []string, pattern string, minLength, maxLength int) []string {
 	filtered := make([]string, 0)
 	compiled := regexp.MustCompile(pattern)
 
 	for _, word := range words {
 		trimmed := strings.TrimSpace(word)
-		if compiled.MatchString(trimmed) && len(trimmed) <= maxLength {
+		length := utf8.RuneCountInString(trimmed)
+		if compiled.MatchString(trimmed) && length >= minLength && length <= maxLength {
 			filtered = append(filtered, trimmed)
 		}
 	}
@@ -76,12 +184,13 @@ func readLines(data []byte) []string {
 		line, err := reader.ReadString('\n')
 		if err != nil {
 			if err == io.EOF {
+				if line != "" {
+					lines = append(lines, line) // no newline at the end
+				}
 				return lines
 			}
 			panic(err) // io error unlikely on buffer
 		}
 		lines = append(lines, line[:len(line)-1])
 	}
-
-	return lines
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// picker returns words in order, over and over.
+func picker(words ...string) func() string {
+	i := -1
+	return func() string {
+		i = (i + 1) % len(words)
+		return words[i]
+	}
+}
+
+func TestComposePhrase(t *testing.T) {
+	assert.Equal(t, "ab cd ef", composePhrase(7, 10, picker("ab", "cd", "ef", "gh")))
+	assert.Equal(t, "ab cd", composePhrase(5, 5, picker("ab", "cd")))
+	assert.Equal(t, "ab cd ab", composePhrase(8, 8, picker("ab", "cdefgh", "cd")))
+	assert.Equal(t, "toolongword", composePhrase(3, 5, picker("toolongword", "x")))
+	assert.Equal(t, "ab", composePhrase(10, 3, picker("ab", "cdef")))
+	assert.Equal(t, "äö üß", composePhrase(5, 5, picker("äö", "üß")))
+}
+
+func TestLimitsValidate(t *testing.T) {
+	assert.NoError(t, Limits{MinPhrase: 30, MinWord: 1, MaxWord: 8}.validate())
+	assert.NoError(t, Limits{MinPhrase: 30, MaxPhrase: 30, MinWord: 2, MaxWord: 2}.validate())
+	assert.EqualError(t, Limits{MinPhrase: -1}.validate(), "length limits must not be negative")
+	assert.EqualError(t, Limits{MaxLine: -1}.validate(), "length limits must not be negative")
+	assert.EqualError(t, Limits{MinPhrase: 31, MaxPhrase: 30}.validate(), "minimum phrase length exceeds maximum")
+	assert.EqualError(t, Limits{MinWord: 9, MaxWord: 8}.validate(), "minimum word length exceeds maximum")
+}
+
+func TestFilterWords(t *testing.T) {
+	words := []string{" apple\n", "Bee", "cat", "dög", "elephant", ""}
+	assert.Equal(t, []string{"apple", "cat", "dög"}, filterWords(words, `^[a-zäöü]+$`, 3, 5))
+	assert.Equal(t, []string{"Bee", "cat", "dög"}, filterWords(words, `.`, 3, 3))
+	assert.Equal(t, []string{}, filterWords(words, `x`, 1, 10))
+}
+
+func TestReadLines(t *testing.T) {
+	assert.Equal(t, []string{"one", "two"}, readLines([]byte("one\ntwo\n")))
+	assert.Equal(t, []string{"one", "two"}, readLines([]byte("one\ntwo")), "the last line needs no newline")
+	assert.Equal(t, []string{"one", ""}, readLines([]byte("one\n\n")))
+	assert.Nil(t, readLines(nil))
+}
@@ -0,0 +1,182 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PlaylistEntry is a phrase source mixed into a session with a weight. A
+// playlist has one entry per line, a weight followed by the kind of source,
+// its argument and options:
+//
+//	# weight kind argument options
+//	60 dictionary en min-word=3
+//	25 lesson shell
+//	15 code ./src max-line=60
+type PlaylistEntry struct {
+	Weight int
+	Kind   string
+	Arg    string
+	// Limits override the limits of the session unless zero.
+	Limits Limits
+	Keys   string
+	// NumberProb overrides the probability of numbers if set, even to zero.
+	NumberProb *float64
+}
+
+// playlistKinds tells which kinds of sources take an argument.
+var playlistKinds = map[string]bool{
+	"dictionary": true, // language
+	"sentences":  true, // language
+	"drill":      true, // formats
+	"words":      true, // file
+	"lesson":     true, // built-in lesson
+	"code":       true, // file or directory
+	"blocks":     true, // file or directory
+	"prose":      true, // file
+	"git":        true, // repository
+	"history":    false,
+}
+
+func parsePlaylist(data []byte) ([]PlaylistEntry, error) {
+	var entries []PlaylistEntry
+	for i, line := range readLines(data) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry, err := parsePlaylistEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("playlist line %d: %v", i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("playlist is empty")
+	}
+	return entries, nil
+}
+
+func parsePlaylistEntry(line string) (PlaylistEntry, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return PlaylistEntry{}, fmt.Errorf("expected weight and source in %q", line)
+	}
+
+	weight, err := strconv.Atoi(fields[0])
+	if err != nil || weight <= 0 {
+		return PlaylistEntry{}, fmt.Errorf("invalid weight %q", fields[0])
+	}
+
+	entry := PlaylistEntry{Weight: weight, Kind: fields[1]}
+	hasArg, ok := playlistKinds[entry.Kind]
+	if !ok {
+		return PlaylistEntry{}, fmt.Errorf("unknown source %q", entry.Kind)
+	}
+
+	options := fields[2:]
+	if hasArg {
+		if len(options) == 0 || strings.Contains(options[0], "=") {
+			return PlaylistEntry{}, fmt.Errorf("source %s needs an argument", entry.Kind)
+		}
+		entry.Arg, options = options[0], options[1:]
+	}
+	if _, ok := locales[entry.Arg]; entry.Kind == "dictionary" && !ok {
+		return PlaylistEntry{}, fmt.Errorf("unknown language %s", entry.Arg)
+	}
+	if _, err := parseDrillFormats(entry.Arg); entry.Kind == "drill" && err != nil {
+		return PlaylistEntry{}, err
+	}
+
+	for _, option := range options {
+		if err := entry.setOption(option); err != nil {
+			return PlaylistEntry{}, err
+		}
+	}
+	return entry, nil
+}
+
+func (e *PlaylistEntry) setOption(option string) error {
+	kv := strings.SplitN(option, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("expected option=value, got %q", option)
+	}
+
+	if kv[0] == "keys" {
+		e.Keys = kv[1]
+		return nil
+	}
+	if kv[0] == "n" {
+		p, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil {
+			return fmt.Errorf("invalid probability %q", kv[1])
+		}
+		e.NumberProb = &p
+		return nil
+	}
+
+	limits := map[string]*int{
+		"min-phrase": &e.Limits.MinPhrase,
+		"max-phrase": &e.Limits.MaxPhrase,
+		"min-word":   &e.Limits.MinWord,
+		"max-word":   &e.Limits.MaxWord,
+		"max-line":   &e.Limits.MaxLine,
+	}
+	limit, ok := limits[kv[0]]
+	if !ok {
+		return fmt.Errorf("unknown option %q", kv[0])
+	}
+	n, err := strconv.Atoi(kv[1])
+	if err != nil || n <= 0 {
+		return fmt.Errorf("invalid length %q", kv[1])
+	}
+	*limit = n
+	return nil
+}
+
+// Source identifies the entry like single sources are identified.
+func (e PlaylistEntry) Source() string {
+	switch e.Kind {
+	case "dictionary":
+		return "lang:" + e.Arg
+	case "sentences":
+		return "sentences:" + e.Arg
+	case "drill":
+		return "drill:" + e.Arg
+	case "lesson":
+		return "lesson:" + e.Arg
+	case "git":
+		return "git:" + e.Arg
+	case "history":
+		return "history"
+	}
+	return e.Arg
+}
+
+// hasPhrases tells whether the material of the entry is phrases or words,
+// which can be left out, rather than sentence templates or drill formats.
+func (e PlaylistEntry) hasPhrases() bool {
+	return e.Kind != "sentences" && e.Kind != "drill"
+}
+
+// mergeLimits returns the limits of the session overridden by those of the
+// entry.
+func (e PlaylistEntry) mergeLimits(limits Limits) Limits {
+	for _, l := range []struct{ entry, session *int }{
+		{&e.Limits.MinPhrase, &limits.MinPhrase},
+		{&e.Limits.MaxPhrase, &limits.MaxPhrase},
+		{&e.Limits.MinWord, &limits.MinWord},
+		{&e.Limits.MaxWord, &limits.MaxWord},
+		{&e.Limits.MaxLine, &limits.MaxLine},
+	} {
+		if *l.entry > 0 {
+			*l.session = *l.entry
+		}
+	}
+	return limits
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePlaylist(t *testing.T) {
+	entries, err := parsePlaylist([]byte(`# weight kind argument options
+60 dictionary de min-word=3 max-word=7 keys=asdf
+25 lesson shell
+
+15 history max-line=40 n=0.1
+`))
+	assert.Nil(t, err)
+	numberProb := 0.1
+	assert.Equal(t, []PlaylistEntry{
+		{Weight: 60, Kind: "dictionary", Arg: "de", Limits: Limits{MinWord: 3, MaxWord: 7}, Keys: "asdf"},
+		{Weight: 25, Kind: "lesson", Arg: "shell"},
+		{Weight: 15, Kind: "history", Limits: Limits{MaxLine: 40}, NumberProb: &numberProb},
+	}, entries)
+
+	for _, line := range []string{
+		"dictionary en", "0 dictionary en", "10 dictionary xx", "10 code", "10 code max-line=5",
+		"10 code . max-line=0", "10 code . foo=1", "10 video .",
+	} {
+		_, err := parsePlaylistEntry(line)
+		assert.NotNil(t, err, line)
+	}
+}
+
+func TestMergeLimits(t *testing.T) {
+	entry := PlaylistEntry{Limits: Limits{MinWord: 3, MaxLine: 40}}
+	assert.Equal(t, Limits{MinPhrase: 30, MinWord: 3, MaxWord: 8, MaxLine: 40},
+		entry.mergeLimits(Limits{MinPhrase: 30, MinWord: 1, MaxWord: 8}))
+}
+
+func TestPlaylistNumberProb(t *testing.T) {
+	state := *NewState(1, DefaultPhrase)
+	state.NumberProb = 1
+	entry, err := parsePlaylistEntry("10 words list.txt n=0")
+	assert.Nil(t, err)
+
+	generator, err := playlistGenerator(state, entry, []string{"foo", "bar"})
+	assert.Nil(t, err)
+	_, phrase := generator(1)
+	assert.NotRegexp(t, `\d`, phrase)
+
+	entry.NumberProb = nil
+	generator, _ = playlistGenerator(state, entry, []string{"foo", "bar"})
+	_, phrase = generator(1)
+	assert.Regexp(t, `^\d+( \d+)*$`, phrase)
+}
+
+func TestPlaylistBadGrammar(t *testing.T) {
+	state, _ := Init([]string{"gotypist", "-curriculum", "off", "-mix", "1 sentences en"}, map[string]string{})
+	state.PlaylistMaterial[0] = []string{"= {Det} {noun}", "det: the"}
+
+	_, commands := startPlaylist(state, time.Now())
+	assert.Equal(t, []Command{Exit{GoodbyeMessage: "playlist sentences en: grammar has no words for {noun}"}}, commands)
+}
+
+func TestPlaylistSkips(t *testing.T) {
+	state, _ := Init([]string{"gotypist", "-curriculum", "off",
+		"-n", "0",
+		"-mix", "1 drill date", "-mix", "1 words /words.txt"}, map[string]string{})
+	state.Skips = Skips{playlistSkipKey: {Never: []string{"date", "foo"}}}
+	state.PlaylistMaterial = [][]string{{"date"}, {"foo", "bar"}}
+
+	state, _ = startPlaylist(state, time.Now())
+	assert.True(t, state.Excluded)
+	dates := 0
+	for i := 0; i < 20; i++ {
+		assert.NotContains(t, strings.Fields(state.Phrase.Text), "foo")
+		if strings.ContainsAny(state.Phrase.Text, "0123456789") {
+			dates++
+		}
+		state = resetPhrase(state, true)
+	}
+	assert.Greater(t, dates, 0, "drill formats are never left out")
+}
+
+func TestPlaylist(t *testing.T) {
+	state, commands := Init([]string{"gotypist", "-curriculum", "off",
+		"-mix", "3 lesson shell", "-mix", "1 dictionary en max-word=3 min-phrase=10"}, map[string]string{})
+
+	for _, command := range commands {
+		if load, ok := command.(LoadPlaylistEntry); ok {
+			for _, message := range RunCommand(load) {
+				state, _ = reduce(state, message, time.Now())
+			}
+		}
+	}
+
+	shell := 0
+	for i := 0; i < 400; i++ {
+		if contains(state.PlaylistMaterial[0], state.Phrase.Text) {
+			shell++
+		} else {
+			for _, word := range strings.Fields(state.Phrase.Text) {
+				assert.LessOrEqual(t, len(word), 3)
+			}
+		}
+		state = resetPhrase(state, true)
+	}
+	assert.InDelta(t, 300, shell, 40)
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Positions maps sequential sources to the index of the line to resume
+// from. Sources are keyed by path and content hash, so that an edited file
+// starts over.
+type Positions map[string]int
+
+func positionKey(source string, data []byte) string {
+	if source == "" || source == "-" {
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+	return source + "@" + hex.EncodeToString(sum[:8])
+}
+
+func parsePositions(data []byte) Positions {
+	var p Positions
+	if err := json.Unmarshal(data, &p); err != nil || p == nil {
+		return Positions{}
+	}
+	return p
+}
+
+func formatPositions(p Positions) []byte {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return append(data, '\n')
+}
+
+// With returns a copy of p with the position of key set to line.
+func (p Positions) With(key string, line int) Positions {
+	c := make(Positions, len(p)+1)
+	for k, v := range p {
+		c[k] = v
+	}
+	c[key] = line
+	return c
+}
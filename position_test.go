@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResumePosition(t *testing.T) {
+	source := Datasource{Data: []byte("first(1)\nsecond(2)\nthird(3)\n"), Source: "/src/main.go"}
+	positions := PositionsData{Data: formatPositions(Positions{positionKey(source.Source, source.Data): 1})}
+	start := func(source Datasource, args ...string) State {
+		state, _ := Init(append([]string{"gotypist", "-c", "-curriculum", "off"}, args...), map[string]string{})
+		state, _ = reduce(state, positions, time.Now())
+		state, _ = reduce(state, source, time.Now())
+		return state
+	}
+
+	state := start(source)
+	assert.Equal(t, "second(2)", state.Phrase.Text)
+	assert.Equal(t, "Line 2/3", linePosition(state))
+
+	state, commands := reduce(state, termbox.Event{Key: termbox.KeyCtrlF}, time.Now())
+	assert.Equal(t, "third(3)", state.Phrase.Text)
+	assert.Equal(t, "Line 3/3", linePosition(state))
+	write := commands[len(commands)-1].(WriteFile)
+	assert.Equal(t, state.Positionsfile, write.Filename)
+	assert.Equal(t, formatPositions(Positions{positionKey(source.Source, source.Data): 2}), write.Data)
+
+	state = start(source, "-restart")
+	assert.Equal(t, "first(1)", state.Phrase.Text)
+	assert.Equal(t, "Line 1/3", linePosition(state))
+
+	state = start(source, "-line", "3")
+	assert.Equal(t, "third(3)", state.Phrase.Text)
+	assert.Equal(t, "Line 3/3", linePosition(state))
+
+	edited := Datasource{Data: []byte("first(1)\nsecond(2)\nfourth(4)\n"), Source: source.Source}
+	state = start(edited)
+	assert.Equal(t, "first(1)", state.Phrase.Text, "an edited file starts over")
+	assert.Equal(t, "Line 1/3", linePosition(state))
+}
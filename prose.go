@@ -0,0 +1,106 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	paragraphSeparator = regexp.MustCompile(`\n\s*\n`)
+	sentenceEnd        = regexp.MustCompile(`[.!?]+["')\]]*$`)
+	proseReplacer      = strings.NewReplacer(
+		"‘", "'", "’", "'", "“", "\"", "”", "\"", "„", "\"",
+		"–", "-", "—", "-", "…", "...", " ", " ",
+	)
+)
+
+// abbreviations do not end a sentence even though they end with a period.
+var abbreviations = map[string]bool{
+	"mr.": true, "mrs.": true, "ms.": true, "dr.": true, "st.": true,
+	"e.g.": true, "i.e.": true, "etc.": true, "vs.": true, "cf.": true,
+}
+
+// splitProse splits text into phrases of at most maxLength characters. Each
+// phrase consists of one or more whole sentences of the same paragraph, in
+// reading order. Only sentences too long to fit are split, at word
+// boundaries. Typographic quotes and dashes are replaced with their plain
+// counterparts so that all of it can be typed.
+func splitProse(text string, maxLength int) []string {
+	var phrases []string
+
+	text = proseReplacer.Replace(strings.ReplaceAll(text, "\r\n", "\n"))
+	for _, paragraph := range paragraphSeparator.Split(text, -1) {
+		phrase := ""
+		for _, sentence := range splitSentences(paragraph) {
+			if phrase != "" && utf8.RuneCountInString(phrase)+1+utf8.RuneCountInString(sentence) <= maxLength {
+				phrase += " " + sentence
+				continue
+			}
+
+			if phrase != "" {
+				phrases = append(phrases, phrase)
+			}
+
+			chunks := wrapWords(strings.Fields(sentence), maxLength)
+			phrases = append(phrases, chunks[:len(chunks)-1]...)
+			phrase = chunks[len(chunks)-1]
+		}
+
+		if phrase != "" {
+			phrases = append(phrases, phrase)
+		}
+	}
+
+	return phrases
+}
+
+func splitSentences(paragraph string) []string {
+	var sentences []string
+	var sentence []string
+
+	for _, word := range strings.Fields(paragraph) {
+		sentence = append(sentence, word)
+		if sentenceEnd.MatchString(word) && !abbreviations[strings.ToLower(word)] {
+			sentences = append(sentences, strings.Join(sentence, " "))
+			sentence = nil
+		}
+	}
+
+	if len(sentence) > 0 {
+		sentences = append(sentences, strings.Join(sentence, " "))
+	}
+
+	return sentences
+}
+
+// wrapWords joins words into lines of at most maxLength characters. Words
+// longer than that are cut.
+func wrapWords(words []string, maxLength int) []string {
+	var lines []string
+	line := ""
+
+	for _, word := range words {
+		for utf8.RuneCountInString(word) > maxLength {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:maxLength]))
+			word = string(runes[maxLength:])
+		}
+
+		if line == "" {
+			line = word
+		} else if utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= maxLength {
+			line += " " + word
+		} else {
+			lines = append(lines, line)
+			line = word
+		}
+	}
+
+	return append(lines, line)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitSentences(t *testing.T) {
+	assert.Equal(t, []string{"One.", "Two!", "Three?"}, splitSentences("One. Two! Three?"))
+	assert.Equal(t, []string{"Ask Dr. Who, e.g. now.", "Then"}, splitSentences("Ask Dr. Who, e.g. now. Then"))
+	assert.Equal(t, []string{`"Quoted."`, "Next..."}, splitSentences(`"Quoted." Next...`))
+	assert.Equal(t, []string{"(So it goes.)", "On"}, splitSentences("(So it\ngoes.)  On"))
+	assert.Nil(t, splitSentences("  \n "))
+}
+
+func TestWrapWords(t *testing.T) {
+	assert.Equal(t, []string{"one two", "three"}, wrapWords([]string{"one", "two", "three"}, 7))
+	assert.Equal(t, []string{"a", "abcd", "efgh", "ij b"}, wrapWords([]string{"a", "abcdefghij", "b"}, 4))
+	assert.Equal(t, []string{"äöüä", "ö"}, wrapWords([]string{"äöüäö"}, 4))
+	assert.Equal(t, []string{""}, wrapWords(nil, 4))
+}
+
+func TestSplitProse(t *testing.T) {
+	text := "It was cold. Mr. Smith left.\r\n\r\nA new “paragraph” — here… " +
+		"And a sentence far too long to fit."
+
+	assert.Equal(t, []string{
+		"It was cold. Mr. Smith left.",
+		`A new "paragraph" - here...`,
+		"And a sentence far too long",
+		"to fit.",
+	}, splitProse(text, 28))
+	assert.Equal(t, []string{
+		"It was cold.",
+		"Mr. Smith left.",
+		`A new "paragraph" - here...`,
+		"And a sentence far too long",
+		"to fit.",
+	}, splitProse(text, 27))
+	assert.Empty(t, splitProse("\n\n  \n", 10))
+}
+
+func TestProseResize(t *testing.T) {
+	source := Datasource{Data: []byte("One two. Three four. Five six. Seven eight.")}
+	state, _ := Init([]string{"gotypist", "-p", "-curriculum", "off"}, map[string]string{})
+	state, _ = reduce(state, TerminalSize{Width: 24}, time.Now())
+	state, _ = reduce(state, source, time.Now())
+	assert.Equal(t, []string{"One two. Three four.", "Five six. Seven eight."}, state.Lines)
+
+	state, _ = reduce(state, termbox.Event{Key: termbox.KeyCtrlF}, time.Now())
+	assert.Equal(t, "Five six. Seven eight.", state.Phrase.Text)
+
+	state, _ = reduce(state, TerminalSize{Width: 14}, time.Now())
+	assert.Equal(t, []string{"One two.", "Three four.", "Five six.", "Seven eight."}, state.Lines)
+	assert.Equal(t, "Five six.", state.Phrase.Text)
+	assert.Equal(t, 0, state.StartLine)
+
+	state, _ = reduce(state, TerminalSize{Width: 14}, time.Now())
+	assert.Equal(t, "Five six.", state.Phrase.Text)
+}
@@ -14,6 +14,8 @@ const (
 	FailPenaltyDuration        = time.Second * FailPenaltySeconds
 	FastErrorHighlightDuration = time.Millisecond * 333
 	ScoreHighlightDuration     = time.Second * 3
+	WrongKeyFlashDuration      = time.Millisecond * 500
+	NoticeDuration             = time.Second * 3
 )
 
 const (
@@ -54,11 +56,18 @@ func render(s State, now time.Time) {
 
 	byteOffset, runeOffset := errorOffset(s.Phrase.Text, s.Phrase.Input)
 
+	// multi-line phrases extend above and below the center line
+	height := strings.Count(s.Phrase.Text, "\n") + 1
+	top := h/2 - (height-1)/2
+	bottom := top + height - 1
+
 	if s.Phrase.ShowFail(now) {
 		left := min(int(s.Phrase.CurrentRound().FailedAt.
 			Add(FailPenaltyDuration).Sub(now).Seconds()+1), FailPenaltySeconds)
 		write(text(failMessage(s.Phrase.CurrentRound().Errors), left).
 			X(w / 2).Y(h / 2).Fg(red | bold).Align(Center))
+	} else if s.Phrase.IsMultiline() {
+		renderBlock(s.Phrase, w, top, byteOffset)
 	} else {
 		x := (w / 2) - (utf8.RuneCountInString(s.Phrase.Text) / 2)
 		write(text(s.Phrase.Text + string('⏎')).X(x).Y(h / 2).Fg(white))
@@ -67,12 +76,40 @@ func render(s State, now time.Time) {
 			X(x).Y(h / 2).Fg(green))
 		write(text(spaced(s.Phrase.Input[byteOffset:])).
 			X(x + runeOffset).Y(h / 2).Fg(black).Bg(red))
+
+		if s.Prose {
+			renderContext(s, w, h/2)
+		}
 	}
 
 	if s.Repeat {
 		write(text("Repeating phrase").X(w - 1).Y(1).Align(Right))
 	}
 
+	if s.Lesson != nil && s.Lesson.Title != "" {
+		write(text("Lesson: %s", s.Lesson.Title).X(w - 1).Y(2).Align(Right))
+	}
+	if s.Lesson != nil && s.Lesson.Keys != "" {
+		write(text("Target keys: %s", s.Lesson.Keys).X(w - 1).Y(3).Align(Right))
+	}
+	if len(s.Lines) > 0 {
+		write(text(linePosition(s)).X(w - 1).Y(4).Align(Right))
+	}
+	if !s.Session.IsZero() {
+		write(text("Session: %s", formatSession(s.Session)).X(w - 1).Y(5).Align(Right))
+	}
+	if difficulty := s.difficulty(s.Phrase.Text); s.Difficulty.IsZero() || s.Difficulty.Contains(difficulty) {
+		write(text("Difficulty: %.0f", difficulty).X(w - 1).Y(6).Align(Right))
+	} else {
+		write(text("Difficulty: %.0f (out of range)", difficulty).X(w - 1).Y(6).Align(Right).Fg(yellow))
+	}
+	if slips := s.Phrase.CurrentRound().ShiftSlips; slips > 0 {
+		write(text("Shift slips: %d, hold Shift with the other hand", slips).X(w - 1).Y(7).Align(Right).Fg(yellow))
+	}
+	if now.Before(s.NoticeUntil) {
+		write(text("%s", s.Notice).X(w - 1).Y(8).Align(Right).Fg(yellow))
+	}
+
 	if now.Before(s.LastScoreUntil) {
 		write(text("   Score: %.0f  +%.0f (%.0f%%)", s.Score, s.LastScore,
 			100.*s.LastScorePercent).X(1).Y(1).Fg(blue | bold))
@@ -85,18 +122,28 @@ func render(s State, now time.Time) {
 	write(text("   Score: %.0f", s.Score).X(1).Y(1))
 	write(text("   Level: %d", level(s.Score)).X(1).Y(2))
 	write(text("Progress: %.0f%%", 100*progress(s.Score)).X(1).Y(3))
+	if s.UnlockedKeys == allKeys {
+		write(text("    Keys: all").X(1).Y(4))
+	} else if s.UnlockedKeys != "" {
+		write(text("    Keys: %s", s.UnlockedKeys).X(1).Y(4))
+	}
 
 	write(text("In %s mode", s.Phrase.Mode.Name()).
-		X(w / 2).Y(h/2 - 4).Fg(s.Phrase.Mode.Attr()).Align(Center))
-	write(text("(%s!)", s.Phrase.Mode.Desc()).X(w / 2).Y(h/2 - 3).Align(Center))
+		X(w / 2).Y(top - 4).Fg(s.Phrase.Mode.Attr()).Align(Center))
+	write(text("(%s!)", s.Phrase.Mode.Desc()).X(w / 2).Y(top - 3).Align(Center))
 
 	seconds, _, _ := computeStats(
 		s.Phrase.Input[:byteOffset], s.Phrase.CurrentRound().StartedAt, now)
 
+	hints := s.Hints
+	if hints == HintsKeyboard && bottom+statsYOffset(hints) >= h-3 {
+		hints = HintsFingers // no room for the keyboard
+	}
+
 	errorsText := text("%3d errors", s.Phrase.CurrentRound().Errors).
-		Y(h/2 + statsYOffset(!s.HideFingers)).Fg(s.Phrase.ErrorCountColor(now))
+		Y(bottom + statsYOffset(hints)).Fg(s.Phrase.ErrorCountColor(now))
 	secondsText := text("%4.1f seconds", seconds).
-		Y(h/2 + statsYOffset(!s.HideFingers))
+		Y(bottom + statsYOffset(hints))
 
 	if s.Phrase.Mode == ModeSlow {
 		write(errorsText.X(w / 2).Align(Center))
@@ -108,16 +155,81 @@ func render(s State, now time.Time) {
 	write(text("What's this fast, slow, medium thing?!").X(1).Y(h - 3))
 	write(text("http://steve-yegge.blogspot.com/2008/09/programmings-dirtiest-little-secret.html").X(1).Y(h - 2))
 
-	if !s.HideFingers {
-		finger := RightPinky // for backspace/enter
+	if hints != HintsOff {
+		finger, shift := RightPinky, NoFinger // for backspace/enter
+		expected := rune(0)
 		if byteOffset < len(s.Phrase.Text) {
-			expected, _ := utf8.DecodeRuneInString(s.Phrase.Text[byteOffset:])
-			finger = FingerMap[expected]
+			expected, _ = utf8.DecodeRuneInString(s.Phrase.Text[byteOffset:])
+			if expected != '\n' {
+				finger = s.FingerMap[expected]
+				shift = s.Layout.ShiftFinger(expected, finger)
+			}
+		}
+
+		if hints == HintsKeyboard {
+			renderKeyboard(s.Layout, w, bottom+2, expected, finger, shift, wrongKey(s.Phrase, now))
+		} else {
+			renderFingers(w, bottom+2, finger, shift)
 		}
-		renderFingers(w, h/2+2, finger)
 	}
 }
 
+// wrongKey returns the character typed by mistake just now, if any.
+func wrongKey(p Phrase, now time.Time) rune {
+	round := p.CurrentRound()
+	if len(round.Typos) == 0 || now.After(round.FailedAt.Add(WrongKeyFlashDuration)) {
+		return 0
+	}
+	wrong, _ := utf8.DecodeRuneInString(round.Typos[len(round.Typos)-1].Actual)
+	return wrong
+}
+
+// renderBlock renders a multi-line phrase, left aligned as a whole.
+func renderBlock(p Phrase, w, y, byteOffset int) {
+	lines := strings.Split(p.Text, "\n")
+	width := 0
+	for _, line := range lines {
+		width = max(width, utf8.RuneCountInString(line))
+	}
+	x := w/2 - width/2
+
+	for i, line := range lines {
+		write(text(line + string('⏎')).X(x).Y(y + i).Fg(white))
+	}
+
+	for i, line := range strings.Split(p.Input[:byteOffset], "\n") {
+		indent := indentation(line)
+		write(text(indent + spaced(line[len(indent):])).X(x).Y(y + i).Fg(green))
+	}
+
+	line, col := cursorPosition(p.Input, byteOffset)
+	for _, r := range p.Input[byteOffset:] {
+		if r == '\n' {
+			termbox.SetCell(x+col, y+line, '⏎', black, red)
+			line, col = line+1, 0
+			continue
+		}
+		write(text(spaced(string(r))).X(x + col).Y(y + line).Fg(black).Bg(red))
+		col++
+	}
+}
+
+// renderContext shows the previous and the next phrase around the current one.
+func renderContext(s State, w, y int) {
+	if s.Seed > 0 && s.Seed <= int64(len(s.Lines)) {
+		write(text(s.Lines[s.Seed-1]).X(w / 2).Y(y - 1).Fg(blue).Align(Center))
+	}
+	if s.Seed+1 < int64(len(s.Lines)) {
+		write(text(s.Lines[s.Seed+1]).X(w / 2).Y(y + 1).Fg(blue).Align(Center))
+	}
+}
+
+// linePosition is the position in a sequential source, counting the lines
+// as -line does.
+func linePosition(s State) string {
+	return fmt.Sprintf("Line %d/%d", s.Seed+1, len(s.Lines))
+}
+
 func fingerYOffset(f Finger) int {
 	if f == LeftThumb || f == RightThumb {
 		return 1
@@ -142,30 +254,108 @@ func fingerSymbol(f Finger) rune {
 	return '|'
 }
 
-func statsYOffset(showFingers bool) int {
-	if showFingers {
+func statsYOffset(hints Hints) int {
+	switch hints {
+	case HintsFingers:
 		return 6
+	case HintsKeyboard:
+		return 8
 	}
 	return 4
 }
 
-func fingerAttr(highlight bool) (termbox.Attribute, termbox.Attribute) {
-	if highlight {
+func fingerAttr(f, finger, shift Finger) (termbox.Attribute, termbox.Attribute) {
+	switch {
+	case f&finger != 0:
 		return black, blue
+	case f&shift != 0:
+		return black, cyan
 	}
 	return termbox.ColorDefault, termbox.ColorDefault
 }
 
-func renderFingers(w, y int, finger Finger) {
+// renderFingers marks the finger to use, and the one holding Shift.
+func renderFingers(w, y int, finger, shift Finger) {
 	x := w/2 - 6
 
 	for i, f := range FingerSequence {
-		fg, bg := fingerAttr(f&finger != 0)
+		fg, bg := fingerAttr(f, finger, shift)
 		termbox.SetCell(
 			x+i+fingerXOffset(f), y+fingerYOffset(f), fingerSymbol(f), fg, bg)
 	}
 }
 
+// keyboardStagger is the horizontal offset of each row of keys.
+var keyboardStagger = []int{0, 3, 4, 5, 5}
+
+const spaceBarWidth = 13
+
+// renderKeyboard draws the keys of layout, highlighting the next key, the
+// keys of the finger to use, the Shift key to hold, and a key pressed by
+// mistake.
+func renderKeyboard(layout Layout, w, y int, next rune, finger, shift Finger, wrong rune) {
+	labels := layout.Labels()
+
+	width := 0
+	minBottom, maxBottom := 0, -1
+	for position := range labels {
+		row, col := position[0], position[1]
+		if row >= 0 && row < len(keyboardStagger) {
+			width = max(width, keyboardStagger[row]+2*col+1)
+		}
+		if row == 3 {
+			minBottom, maxBottom = min(minBottom, col), max(maxBottom, col)
+		}
+	}
+	if maxBottom >= 0 {
+		width = max(width, keyboardStagger[3]+2*(maxBottom+1)+1)
+	}
+	x := w/2 - width/2
+
+	nextKey, hasNext := layout.KeyOf(next)
+	wrongKey, hasWrong := layout.KeyOf(wrong)
+	attrs := func(key Key) (termbox.Attribute, termbox.Attribute) {
+		switch {
+		case hasWrong && key.Row == wrongKey.Row && key.Col == wrongKey.Col:
+			return black, red
+		case hasNext && key.Row == nextKey.Row && key.Col == nextKey.Col:
+			return black, blue
+		case key.Finger&finger != 0:
+			return blue, termbox.ColorDefault
+		}
+		return termbox.ColorDefault, termbox.ColorDefault
+	}
+
+	for position, r := range labels {
+		row, col := position[0], position[1]
+		if row < 0 || row >= len(keyboardStagger) {
+			continue
+		}
+		fg, bg := attrs(layout.Keys[r])
+
+		if r == ' ' {
+			for i := 0; i < spaceBarWidth; i++ {
+				termbox.SetCell(w/2-spaceBarWidth/2+i, y+row, '─', fg, bg)
+			}
+			continue
+		}
+		termbox.SetCell(x+keyboardStagger[row]+2*col, y+row, r, fg, bg)
+	}
+
+	if maxBottom >= 0 {
+		for _, key := range []Key{
+			{Finger: LeftPinky, Row: 3, Col: minBottom - 1},
+			{Finger: RightPinky, Row: 3, Col: maxBottom + 1},
+		} {
+			fg, bg := attrs(key)
+			if key.Finger&shift != 0 {
+				fg, bg = black, cyan
+			}
+			termbox.SetCell(x+keyboardStagger[3]+2*key.Col, y+3, '⇧', fg, bg)
+		}
+	}
+}
+
 func text(t string, args ...interface{}) *printSpec {
 	s := &printSpec{}
 	if len(args) > 0 {
@@ -0,0 +1,210 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Session identifies a sequence of phrases. The same material, settings,
+// and seed (or start line, for sequential sources) always yield the same
+// phrases, so a session can be shared as a code.
+type Session struct {
+	// Source names built-in material ("lang:en", "lesson:shell"), it is
+	// empty for files which have to be passed along with the code.
+	Source     string
+	Hash       [4]byte
+	Seed       int64
+	Line       int
+	Keys       string
+	Limits     Limits
+	NumberProb float64
+	Difficulty DifficultyRange
+	Ramp       bool
+	// Layout is the keyboard layout scoring the difficulty, if that
+	// matters.
+	Layout string
+	// Unlocks are the keys unlocked by the curriculum during the session.
+	Unlocks []Unlock
+}
+
+// Unlock changes the keys to drill before the given phrase of a session,
+// counting from zero.
+type Unlock struct {
+	Phrase int
+	Keys   string
+}
+
+const sessionVersion = 4
+
+const (
+	sessionAllKeys = 1 << iota
+	sessionSomeKeys
+	sessionRamp
+)
+
+var errSessionCode = errors.New("invalid session code")
+
+func materialHash(material []string) [4]byte {
+	var hash [4]byte
+	sum := sha256.Sum256([]byte(strings.Join(material, "\n")))
+	copy(hash[:], sum[:])
+	return hash
+}
+
+// sessionSource returns the part of a source identity that is the same
+// for everyone.
+func sessionSource(source string) string {
+	if strings.HasPrefix(source, "lang:") || strings.HasPrefix(source, "lesson:") ||
+		strings.HasPrefix(source, "sentences:") || strings.HasPrefix(source, "drill:") {
+		return source
+	}
+	return ""
+}
+
+func (s Session) IsZero() bool {
+	return s.Hash == [4]byte{}
+}
+
+// formatSession encodes a session compactly: printable ASCII keys are
+// stored as a bitset, numbers as varints.
+func formatSession(s Session) string {
+	var buf bytes.Buffer
+	var scratch [binary.MaxVarintLen64]byte
+
+	putInt := func(n int64) { buf.Write(scratch[:binary.PutVarint(scratch[:], n)]) }
+	putString := func(str string) {
+		putInt(int64(len(str)))
+		buf.WriteString(str)
+	}
+
+	flags := byte(0)
+	if s.Keys == allKeys {
+		flags |= sessionAllKeys
+	} else if s.Keys != "" {
+		flags |= sessionSomeKeys
+	}
+	if s.Ramp {
+		flags |= sessionRamp
+	}
+
+	buf.WriteByte(sessionVersion)
+	buf.WriteByte(flags)
+	buf.Write(s.Hash[:])
+	putInt(s.Seed)
+	putInt(int64(s.Line))
+	for _, n := range []int{s.Limits.MinPhrase, s.Limits.MaxPhrase, s.Limits.MinWord, s.Limits.MaxWord, s.Limits.MaxLine} {
+		putInt(int64(n))
+	}
+	putInt(int64(s.Difficulty.Min))
+	putInt(int64(s.Difficulty.Max))
+	putString(strconv.FormatFloat(s.NumberProb, 'g', -1, 64))
+	putString(s.Source)
+	putString(s.Layout)
+
+	if flags&sessionSomeKeys != 0 {
+		var ascii [12]byte
+		var other strings.Builder
+		for _, r := range s.Keys {
+			if r >= ' ' && r <= '~' {
+				ascii[(r-' ')/8] |= 1 << ((r - ' ') % 8)
+			} else {
+				other.WriteRune(r)
+			}
+		}
+		buf.Write(ascii[:])
+		putString(other.String())
+	}
+
+	putInt(int64(len(s.Unlocks)))
+	for _, unlock := range s.Unlocks {
+		putInt(int64(unlock.Phrase))
+		putString(unlock.Keys)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes())
+}
+
+func parseSession(code string) (Session, error) {
+	data, err := base64.RawURLEncoding.DecodeString(code)
+	if err != nil || len(data) < 6 || data[0] != sessionVersion {
+		return Session{}, errSessionCode
+	}
+
+	var s Session
+	flags := data[1]
+	copy(s.Hash[:], data[2:6])
+	r := bytes.NewReader(data[6:])
+
+	getInt := func() int64 {
+		n, e := binary.ReadVarint(r)
+		if e != nil {
+			err = errSessionCode
+		}
+		return n
+	}
+	getString := func() string {
+		n := getInt()
+		if err != nil || n < 0 || n > int64(r.Len()) {
+			err = errSessionCode
+			return ""
+		}
+		str := make([]byte, n)
+		r.Read(str)
+		return string(str)
+	}
+
+	s.Seed = getInt()
+	s.Line = int(getInt())
+	for _, n := range []*int{&s.Limits.MinPhrase, &s.Limits.MaxPhrase, &s.Limits.MinWord, &s.Limits.MaxWord, &s.Limits.MaxLine} {
+		*n = int(getInt())
+	}
+	s.Difficulty.Min = int(getInt())
+	s.Difficulty.Max = int(getInt())
+	s.Ramp = flags&sessionRamp != 0
+	numberProb := getString()
+	s.Source = getString()
+	s.Layout = getString()
+
+	if flags&sessionAllKeys != 0 {
+		s.Keys = allKeys
+	} else if flags&sessionSomeKeys != 0 {
+		var ascii [12]byte
+		if n, _ := r.Read(ascii[:]); n < len(ascii) {
+			return Session{}, errSessionCode
+		}
+		var keys strings.Builder
+		for i := 0; i < 8*len(ascii); i++ {
+			if ascii[i/8]&(1<<(i%8)) != 0 {
+				keys.WriteRune(rune(' ' + i))
+			}
+		}
+		keys.WriteString(getString())
+		s.Keys = keys.String()
+	}
+
+	unlocks := getInt()
+	if unlocks < 0 || unlocks > int64(r.Len()) {
+		return Session{}, errSessionCode
+	}
+	for i := int64(0); i < unlocks && err == nil; i++ {
+		s.Unlocks = append(s.Unlocks, Unlock{Phrase: int(getInt()), Keys: getString()})
+	}
+
+	if err != nil {
+		return Session{}, err
+	}
+	if s.NumberProb, err = strconv.ParseFloat(numberProb, 64); err != nil {
+		return Session{}, errSessionCode
+	}
+	if s.Limits.validate() != nil || s.Difficulty.validate() != nil {
+		return Session{}, errSessionCode
+	}
+
+	return s, nil
+}
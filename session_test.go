@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionCode(t *testing.T) {
+	session := Session{
+		Source:     "lang:de",
+		Hash:       materialHash([]string{"a", "b"}),
+		Seed:       -1234567890123,
+		Line:       7,
+		Keys:       "asdfghjkl;'äöü",
+		Limits:     Limits{MinPhrase: 30, MaxPhrase: 78, MinWord: 1, MaxWord: 8, MaxLine: 78},
+		NumberProb: 0.1,
+		Difficulty: DifficultyRange{Min: 10, Max: 60},
+		Ramp:       true,
+		Layout:     "colemak",
+		Unlocks:    []Unlock{{Phrase: 12, Keys: "asdfgh"}, {Phrase: 40, Keys: allKeys}},
+	}
+
+	parsed, err := parseSession(formatSession(session))
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []rune(session.Keys), []rune(parsed.Keys))
+	parsed.Keys = session.Keys
+	assert.Equal(t, session, parsed)
+
+	session.Keys = allKeys
+	parsed, err = parseSession(formatSession(session))
+	assert.Nil(t, err)
+	assert.Equal(t, session, parsed)
+
+	_, err = parseSession("garbage")
+	assert.Equal(t, errSessionCode, err)
+}
+
+func TestReplaySession(t *testing.T) {
+	words := Datasource{Data: []byte("foo\nbar\nbaz\nquux\nasdf\njkl\n"), Source: "lang:en"}
+	phrases := func(state State) []string {
+		var p []string
+		for i := 0; i < 5; i++ {
+			p = append(p, state.Phrase.Text)
+			state = resetPhrase(state, true)
+		}
+		return p
+	}
+
+	state, _ := Init([]string{"gotypist", "-curriculum", "off"}, map[string]string{})
+	state, _ = reduce(state, words, time.Unix(42, 0))
+	code := formatSession(state.Session)
+
+	replayed, _ := Init([]string{"gotypist", "-session", code}, map[string]string{})
+	replayed, _ = reduce(replayed, words, time.Unix(1000, 0))
+	assert.Equal(t, phrases(state), phrases(replayed))
+
+	other := Datasource{Data: []byte("other\nwords\n"), Source: "lang:en"}
+	replayed, _ = Init([]string{"gotypist", "-session", code}, map[string]string{})
+	_, commands := reduce(replayed, other, time.Unix(1000, 0))
+	assert.IsType(t, Exit{}, commands[0])
+}
+
+func TestReplayUnlocks(t *testing.T) {
+	words := Datasource{Data: []byte("sad\nfad\nride\nuser\nquiz\nfeed\n"), Source: "lang:en"}
+	phrases := func(state State) ([]string, State) {
+		var p []string
+		for i := 0; i < 6; i++ {
+			if i == 3 {
+				state.Score = requiredScore(3) + 1
+			}
+			state = resetPhrase(state, true)
+			p = append(p, state.Phrase.Text)
+		}
+		return p, state
+	}
+
+	state, _ := Init([]string{"gotypist"}, map[string]string{})
+	state, _ = reduce(state, CurriculumData{Data: []byte(testCurriculum)}, time.Now())
+	state, _ = reduce(state, words, time.Unix(42, 0))
+	original, state := phrases(state)
+	assert.Equal(t, "asdfeiru", state.UnlockedKeys)
+	assert.Equal(t, "asdf", state.Session.Keys)
+	assert.Equal(t, []Unlock{{Phrase: 4, Keys: "asdfeiru"}}, state.Session.Unlocks)
+	assert.Equal(t, time.Unix(42, 0).UnixNano(), state.Session.Seed, "the start of the session")
+
+	replayed, _ := Init([]string{"gotypist", "-session", formatSession(state.Session)}, map[string]string{})
+	replayed, _ = reduce(replayed, words, time.Unix(1000, 0))
+	replay, replayed := phrases(replayed)
+	assert.Equal(t, original, replay)
+	assert.Equal(t, formatSession(state.Session), formatSession(replayed.Session))
+}
@@ -0,0 +1,128 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Skips keeps track of the phrases skipped with Ctrl-F and of the phrases
+// and words never to be shown again, by source. Unlike positions, sources
+// are not keyed by content, so that the list survives edits.
+type Skips map[string]SkipList
+
+type SkipList struct {
+	// Skipped counts how often each phrase was skipped.
+	Skipped map[string]int `json:"skipped,omitempty"`
+	Never   []string       `json:"never,omitempty"`
+}
+
+func skipKey(source string) string {
+	if source == "-" {
+		return ""
+	}
+	return source
+}
+
+func parseSkips(data []byte) Skips {
+	var s Skips
+	if err := json.Unmarshal(data, &s); err != nil || s == nil {
+		return Skips{}
+	}
+	return s
+}
+
+func formatSkips(s Skips) []byte {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return append(data, '\n')
+}
+
+// maxSkipped is the number of skipped phrases counted per source. The least
+// skipped phrases make room for new ones.
+const maxSkipped = 100
+
+// WithSkipped returns a copy of s with one more skip of phrase.
+func (s Skips) WithSkipped(key, phrase string) Skips {
+	c, list := s.copyList(key)
+	list.Skipped = make(map[string]int, len(s[key].Skipped)+1)
+	for p, n := range s[key].Skipped {
+		list.Skipped[p] = n
+	}
+	for list.Skipped[phrase] == 0 && len(list.Skipped) >= maxSkipped {
+		delete(list.Skipped, leastSkipped(list.Skipped))
+	}
+	list.Skipped[phrase]++
+	c[key] = list
+	return c
+}
+
+// leastSkipped returns the phrase skipped the fewest times, the first in
+// alphabetical order among equals.
+func leastSkipped(skipped map[string]int) string {
+	least := ""
+	for p, n := range skipped {
+		if least == "" || n < skipped[least] || n == skipped[least] && p < least {
+			least = p
+		}
+	}
+	return least
+}
+
+// WithNever returns a copy of s with item never to be shown again.
+func (s Skips) WithNever(key, item string) Skips {
+	c, list := s.copyList(key)
+	if !list.IsNever(item) {
+		list.Never = append(append([]string{}, list.Never...), item)
+	}
+	c[key] = list
+	return c
+}
+
+func (s Skips) copyList(key string) (Skips, SkipList) {
+	c := make(Skips, len(s)+1)
+	for k, v := range s {
+		c[k] = v
+	}
+	return c, c[key]
+}
+
+func (l SkipList) IsNever(item string) bool {
+	for _, never := range l.Never {
+		if never == item {
+			return true
+		}
+	}
+	return false
+}
+
+// Without returns items except for those never to be shown again.
+func (l SkipList) Without(items []string) []string {
+	if len(l.Never) == 0 {
+		return items
+	}
+
+	kept := make([]string, 0, len(items))
+	for _, item := range items {
+		if !l.IsNever(item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// wordAt returns the word of text at byte offset, or the one right before
+// it.
+func wordAt(text string, offset int) string {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	start := strings.LastIndexAny(strings.TrimRight(text[:offset], " "), " \n") + 1
+	end := strings.IndexAny(text[start:], " \n")
+	if end < 0 {
+		return text[start:]
+	}
+	return text[start : start+end]
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkips(t *testing.T) {
+	skips := Skips{}.WithSkipped("a", "foo").WithSkipped("a", "foo").WithNever("a", "bar").WithNever("a", "bar")
+	assert.Equal(t, Skips{"a": {Skipped: map[string]int{"foo": 2}, Never: []string{"bar"}}}, skips)
+	assert.Equal(t, skips, parseSkips(formatSkips(skips)))
+	assert.Equal(t, []string{"foo", "baz"}, skips["a"].Without([]string{"foo", "bar", "baz"}))
+	assert.Equal(t, Skips{}, parseSkips([]byte("garbage")))
+}
+
+func TestWordAt(t *testing.T) {
+	assert.Equal(t, "foo", wordAt("foo bar baz", 0))
+	assert.Equal(t, "foo", wordAt("foo bar baz", 4))
+	assert.Equal(t, "bar", wordAt("foo bar baz", 5))
+	assert.Equal(t, "baz", wordAt("foo bar baz", 11))
+}
+
+func TestNeverShow(t *testing.T) {
+	source := Datasource{Data: []byte("one line\ntwo lines\nred lines\n"), Source: "/code.txt"}
+	state, _ := Init([]string{"gotypist", "-c", "-curriculum", "off"}, map[string]string{"HOME": "/home"})
+	state, _ = reduce(state, source, time.Now())
+	assert.Equal(t, "one line", state.Phrase.Text)
+
+	state, commands := reduce(state, termbox.Event{Key: termbox.KeyCtrlX}, time.Now())
+	assert.Equal(t, "two lines", state.Phrase.Text)
+	write := commands[0].(WriteFile)
+	assert.Equal(t, "/home/.gotypist.skips", write.Filename)
+	assert.Equal(t, formatSkips(Skips{"/code.txt": {Never: []string{"one line"}}}), write.Data)
+
+	next, _ := Init([]string{"gotypist", "-c", "-curriculum", "off"}, map[string]string{"HOME": "/home"})
+	next, _ = reduce(next, SkipsData{Data: write.Data}, time.Now())
+	next, _ = reduce(next, source, time.Now())
+	assert.Equal(t, []string{"two lines", "red lines"}, next.Lines)
+	assert.True(t, next.Session.IsZero())
+}
+
+func TestSkipsSavedWithNever(t *testing.T) {
+	source := Datasource{Data: []byte("one line\ntwo lines\nred lines\n"), Source: "/code.txt"}
+	state, _ := Init([]string{"gotypist", "-c", "-restart", "-curriculum", "off"}, map[string]string{"HOME": "/home"})
+	state, _ = reduce(state, source, time.Now())
+
+	state, commands := reduce(state, termbox.Event{Key: termbox.KeyCtrlF}, time.Now())
+	for _, command := range commands {
+		write, ok := command.(WriteFile)
+		assert.False(t, ok && write.Filename == "/home/.gotypist.skips")
+	}
+	assert.Equal(t, Skips{"/code.txt": {Skipped: map[string]int{"one line": 1}}}, state.Skips)
+
+	_, commands = reduce(state, termbox.Event{Key: termbox.KeyCtrlX}, time.Now())
+	write := commands[0].(WriteFile)
+	assert.Equal(t, "/home/.gotypist.skips", write.Filename)
+	assert.Equal(t, formatSkips(Skips{"/code.txt": {
+		Skipped: map[string]int{"one line": 1},
+		Never:   []string{"two lines"},
+	}}), write.Data)
+}
+
+func TestSkippedCap(t *testing.T) {
+	skips := Skips{}.WithSkipped("a", "often").WithSkipped("a", "often")
+	for i := 0; i < maxSkipped; i++ {
+		skips = skips.WithSkipped("a", strconv.Itoa(i))
+	}
+	assert.Len(t, skips["a"].Skipped, maxSkipped)
+	assert.Equal(t, 2, skips["a"].Skipped["often"])
+	assert.NotContains(t, skips["a"].Skipped, "0")
+	assert.Contains(t, skips["a"].Skipped, strconv.Itoa(maxSkipped-1))
+
+	skips = skips.WithSkipped("a", "often")
+	assert.Len(t, skips["a"].Skipped, maxSkipped)
+	assert.Equal(t, 3, skips["a"].Skipped["often"])
+}
+
+func TestNeverShowWord(t *testing.T) {
+	source := Datasource{Data: []byte("foo\nbar\n"), Source: "/words.txt"}
+	state, _ := Init([]string{"gotypist", "-curriculum", "off", "-min-phrase", "3", "-max-phrase", "3"},
+		map[string]string{"HOME": "/home"})
+	state, _ = reduce(state, source, time.Now())
+	assert.False(t, state.Session.IsZero())
+	word := state.Phrase.Text
+
+	state, commands := reduce(state, termbox.Event{Key: termbox.KeyCtrlX}, time.Now())
+	assert.IsType(t, WriteFile{}, commands[0])
+	assert.NotContains(t, state.Words, word)
+	assert.NotEqual(t, word, state.Phrase.Text)
+	assert.True(t, state.Session.IsZero())
+
+	_, commands = reduce(state, termbox.Event{Key: termbox.KeyCtrlX}, time.Now())
+	assert.Equal(t, Exit{GoodbyeMessage: allSkipped}, commands[len(commands)-1])
+}
+
+func TestNeverShowLine(t *testing.T) {
+	source := Datasource{Data: []byte("one line\ntwo lines\nred lines\n"), Source: "/code.txt"}
+	state, _ := Init([]string{"gotypist", "-c", "-curriculum", "off"}, map[string]string{})
+	state, _ = reduce(state, source, time.Now())
+	state, _ = reduce(state, termbox.Event{Key: termbox.KeyCtrlF}, time.Now())
+	state, _ = reduce(state, termbox.Event{Key: termbox.KeyCtrlX}, time.Now())
+	assert.Equal(t, []string{"one line", "red lines"}, state.Lines)
+	assert.Equal(t, "red lines", state.Phrase.Text)
+
+	state, _ = reduce(state, termbox.Event{Key: termbox.KeyCtrlX}, time.Now())
+	assert.Equal(t, []string{"one line"}, state.Lines)
+	assert.Equal(t, "one line", state.Phrase.Text)
+}
+
+func TestNeverShowRandomLine(t *testing.T) {
+	lesson := "---\norder: random\n---\nfirst\nsecond\n"
+	state, _ := Init([]string{"gotypist", "-curriculum", "off"}, map[string]string{})
+	state, _ = reduce(state, LessonData{Name: "test", Data: []byte(lesson)}, time.Now())
+	assert.Equal(t, "lesson:test", state.SkipKey)
+
+	never := state.Phrase.Text
+	state, _ = reduce(state, termbox.Event{Key: termbox.KeyCtrlX}, time.Now())
+	for seed := int64(0); seed < 50; seed++ {
+		_, phrase := state.PhraseGenerator(seed)
+		assert.NotEqual(t, never, phrase)
+	}
+}
+
+func TestNeverShowUnsupported(t *testing.T) {
+	state, _ := Init([]string{"gotypist", "-curriculum", "off"}, map[string]string{})
+	state, _ = reduce(state, DrillData{Formats: []string{"date"}}, time.Now())
+	phrase := state.Phrase.Text
+
+	now := time.Now()
+	state, commands := reduce(state, termbox.Event{Key: termbox.KeyCtrlX}, now)
+	assert.Equal(t, []Command{Interrupt{NoticeDuration}}, commands)
+	assert.Equal(t, phrase, state.Phrase.Text)
+	assert.Equal(t, cannotNever, state.Notice)
+	assert.Equal(t, now.Add(NoticeDuration), state.NoticeUntil)
+}
+
+func TestTypSkipKey(t *testing.T) {
+	state, _ := Init([]string{"gotypist", "-curriculum", "off"}, map[string]string{})
+	state, _ = reduce(state, TypData{Name: "q.typ", Source: "/q.typ", Data: []byte(typScript)}, time.Now())
+	assert.Equal(t, "/q.typ", state.SkipKey)
+}
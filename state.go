@@ -2,6 +2,11 @@
 package main
 
 import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"time"
 	"unicode/utf8"
 
@@ -19,6 +24,7 @@ type Round struct {
 	FinishedAt time.Time
 	Errors     int
 	Typos      []Typo
+	ShiftSlips int
 }
 
 type Phrase struct {
@@ -28,13 +34,75 @@ type Phrase struct {
 	Mode   Mode
 }
 
+// Limits restrict the length of phrases, words, and lines of code. Zero
+// maximums default to the terminal width.
+type Limits struct {
+	MinPhrase int
+	MaxPhrase int
+	MinWord   int
+	MaxWord   int
+	MaxLine   int
+}
+
+func (l Limits) validate() error {
+	if l.MinPhrase < 0 || l.MaxPhrase < 0 || l.MinWord < 0 || l.MaxWord < 0 || l.MaxLine < 0 {
+		return errors.New("length limits must not be negative")
+	}
+	if l.MaxPhrase > 0 && l.MinPhrase > l.MaxPhrase {
+		return errors.New("minimum phrase length exceeds maximum")
+	}
+	if l.MinWord > l.MaxWord {
+		return errors.New("minimum word length exceeds maximum")
+	}
+	return nil
+}
+
 type State struct {
 	Codelines        bool
+	Blocks           bool
+	TypeIndent       bool
+	Prose            bool
+	ProseSource      Datasource
+	HistoryRandom    bool
+	HistoryFiles     ReadShellHistory
+	Playlist         []PlaylistEntry
+	PlaylistMaterial [][]string
+	Exec             bool
+	Lines            []string
+	StartLine        int
+	Restart          bool
+	Positions        Positions
+	PositionKey      string
+	Positionsfile    string
+	Skips            Skips
+	SkipKey          string
+	Skipsfile        string
+	Excluded         bool
+	Width            int
+	Limits           Limits
+	Difficulty       DifficultyRange
+	Ramp             bool
 	NumberProb       float64
 	Seed             int64
+	PhraseCount      int
+	FixedSeed        int64
+	Session          Session
+	Replay           *Session
 	PhraseGenerator  PhraseFunc
 	Phrase           Phrase
-	HideFingers      bool
+	Modes            []Mode
+	Lesson           *Lesson
+	Locale           Locale
+	FingerMap        map[rune]Finger
+	Layout           Layout
+	LayoutCheck      bool
+	Remap            map[rune]rune
+	Analysis         []Layout
+	Words            []string
+	DrillKeys        string
+	Curriculum       Curriculum
+	UnlockedKeys     string
+	Hints            Hints
 	Repeat           bool
 	RageQuit         bool
 	Statsfile        string
@@ -42,6 +110,8 @@ type State struct {
 	LastScore        float64
 	LastScorePercent float64
 	LastScoreUntil   time.Time
+	Notice           string
+	NoticeUntil      time.Time
 }
 
 func reduce(s State, msg Message, now time.Time) (State, []Command) {
@@ -49,10 +119,46 @@ func reduce(s State, msg Message, now time.Time) (State, []Command) {
 	case error:
 		return s, []Command{Exit{GoodbyeMessage: m.Error()}}
 	case Datasource:
-		return reduceDatasource(s, m.Data, now)
+		return reduceDatasource(s, m, now)
+	case CodeTree:
+		return reduceCodeTree(s, m)
+	case GitHistory:
+		return reduceGitHistory(s, m)
+	case ShellHistory:
+		return reduceShellHistory(s, m, now)
+	case SentenceData:
+		return reduceSentenceData(s, m, now)
+	case DrillData:
+		return reduceDrillData(s, m, now)
+	case PlaylistFile:
+		return reducePlaylistFile(s, m)
+	case PlaylistData:
+		return reducePlaylistData(s, m, now)
+	case ExecPhrase:
+		return reduceExecPhrase(s, m)
+	case PositionsData:
+		s.Positions = parsePositions(m.Data)
+		return s, Noop
+	case SkipsData:
+		s.Skips = parseSkips(m.Data)
+		return s, Noop
+	case LessonData:
+		return reduceLessonData(s, m.Name, "lesson:"+m.Name, m.Data, now)
+	case TypData:
+		return reduceTypData(s, m, now)
+	case LessonList:
+		return s, []Command{Exit{GoodbyeMessage: formatLessonList(m.Lessons)}}
 	case StatsData:
 		s.Score = getTotalScore(m.Data)
-		return s, Noop
+		return reloadCurriculum(s), Noop
+	case TerminalSize:
+		return reduceTerminalSize(s, m)
+	case CurriculumData:
+		return reduceCurriculumData(s, m.Data)
+	case LayoutData:
+		return reduceLayoutData(s, m)
+	case CorpusData:
+		return reduceCorpusData(s, m)
 	case termbox.Event:
 		return reduceEvent(s, m, now)
 	}
@@ -76,11 +182,13 @@ func reduceEvent(s State, ev termbox.Event, now time.Time) (State, []Command) {
 	case termbox.KeyBackspace, termbox.KeyBackspace2:
 		return reduceBackspace(s)
 	case termbox.KeyCtrlF:
-		s = resetPhrase(s, true)
+		return advancePhrase(recordSkip(s), true)
+	case termbox.KeyCtrlX:
+		return reduceNever(s, now)
 	case termbox.KeyCtrlR:
 		s.Repeat = !s.Repeat
 	case termbox.KeyCtrlI:
-		s.HideFingers = !s.HideFingers
+		s.Hints = (s.Hints + 1) % hintModes
 	case termbox.KeyEnter, termbox.KeyCtrlJ:
 		return reduceEnter(s, now)
 	default:
@@ -102,6 +210,9 @@ func reduceBackspace(s State) (State, []Command) {
 
 func reduceEnter(s State, now time.Time) (State, []Command) {
 	if s.Phrase.Input != s.Phrase.Text {
+		if s.Phrase.IsMultiline() {
+			return reduceRune(s, '\n', now)
+		}
 		return s, Noop
 	}
 
@@ -112,10 +223,17 @@ func reduceEnter(s State, now time.Time) (State, []Command) {
 	}
 
 	s.Phrase.CurrentRound().FinishedAt = now
-	if s.Phrase.Mode != ModeNormal {
-		s.Phrase.Mode++
+	if next, ok := nextMode(s.modes(), s.Phrase.Mode); ok {
+		s.Phrase.Mode = next
 		s.Phrase.Input = ""
-		return s, []Command{logCmd}
+		return s, append([]Command{logCmd}, resultCommands(s, logCmd.Data, nil)...)
+	}
+
+	if !isFullCycle(s.modes()) {
+		// partial cycles are logged but not scored
+		cmds := append([]Command{logCmd}, resultCommands(s, logCmd.Data, nil)...)
+		s, nextCmds := advancePhrase(s, false)
+		return s, append(cmds, nextCmds...)
 	}
 
 	s.LastScoreUntil = now.Add(ScoreHighlightDuration)
@@ -123,9 +241,41 @@ func reduceEnter(s State, now time.Time) (State, []Command) {
 	s.LastScore = score
 	s.LastScorePercent = score / maxScore(s.Phrase.Text)
 	s.Score += score
-	s = resetPhrase(s, false)
+	cmds := append([]Command{logCmd, Interrupt{ScoreHighlightDuration}}, resultCommands(s, logCmd.Data, &score)...)
+	s, nextCmds := advancePhrase(s, false)
 
-	return s, []Command{logCmd, Interrupt{ScoreHighlightDuration}}
+	return s, append(cmds, nextCmds...)
+}
+
+// resultCommands send a finished round to the -exec program, if any.
+func resultCommands(s State, stats []byte, score *float64) []Command {
+	if !s.Exec {
+		return Noop
+	}
+	return []Command{ReportResult{Data: execResult(stats, score)}}
+}
+
+// advancePhrase moves on to the next phrase after a finished or skipped
+// one. The -exec program is asked for it, all other sources are pure.
+func advancePhrase(s State, forceNext bool) (State, []Command) {
+	if !s.Exec {
+		return savePosition(resetPhrase(s, forceNext))
+	}
+
+	if s.Repeat && !forceNext {
+		return reduceExecPhrase(s, ExecPhrase{Text: s.Phrase.Text})
+	}
+	return s, []Command{RequestPhrase{}}
+}
+
+func reduceExecPhrase(s State, phrase ExecPhrase) (State, []Command) {
+	if strings.TrimSpace(phrase.Text) == "" {
+		return s, []Command{Exit{GoodbyeMessage: "exec: the program sent an empty phrase"}}
+	}
+
+	s.Phrase = *NewPhrase(phrase.Text)
+	s.Phrase.Mode = s.modes()[0]
+	return s, Noop
 }
 
 func reduceCharInput(s State, ev termbox.Event, now time.Time) (State, []Command) {
@@ -139,10 +289,20 @@ func reduceCharInput(s State, ev termbox.Event, now time.Time) (State, []Command
 	if ch == 0 {
 		return s, Noop
 	}
+	if mapped, ok := s.Remap[ch]; ok {
+		ch = mapped
+	}
+
+	return reduceRune(s, ch, now)
+}
 
+func reduceRune(s State, ch rune, now time.Time) (State, []Command) {
 	exp := s.Phrase.expected()
 	if ch == exp {
 		s.Phrase.Input += string(ch)
+		if ch == '\n' && !s.TypeIndent {
+			s.Phrase.Input += indentation(s.Phrase.Text[len(s.Phrase.Input):])
+		}
 		return s, Noop
 	}
 
@@ -152,6 +312,9 @@ func reduceCharInput(s State, ev termbox.Event, now time.Time) (State, []Command
 				Expected: string(exp),
 				Actual:   string(ch),
 			})
+		if s.Layout.ShiftSlip(exp, ch) {
+			s.Phrase.CurrentRound().ShiftSlips++
+		}
 	}
 
 	s.Phrase.CurrentRound().Errors++
@@ -175,40 +338,821 @@ func reduceCharInput(s State, ev termbox.Event, now time.Time) (State, []Command
 	return s, Noop
 }
 
-func reduceDatasource(state State, data []byte, now time.Time) (State, []Command) {
-	var generator func([]string) PhraseFunc
-	var filter func([]string) []string
+func reduceDatasource(state State, source Datasource, now time.Time) (State, []Command) {
+	if !state.Prose && !state.Codelines && !state.Blocks && isLesson(source.Data) {
+		return reduceLessonData(state, filepath.Base(source.Source), source.Source, source.Data, now)
+	}
 
-	if state.Codelines {
-		filter = func(items []string) []string { return filterWords(items, `^[^/][^/]`, 80) }
-		generator = SequentialLine
-	} else {
-		filter = func(items []string) []string { return filterWords(items, `^[a-z]+$`, 8) }
-		generator = func(words []string) PhraseFunc { return RandomPhrase(words, 30, state.NumberProb) }
-		state.Seed = now.UnixNano()
+	key := positionKey(source.Source, source.Data)
+	if state.Prose {
+		return reduceProse(state, source, key)
+	}
+
+	if state.Codelines || state.Blocks {
+		lines := fileCode(state, source)
+		if len(lines) == 0 {
+			return state, []Command{Exit{GoodbyeMessage: "datafile contains no usable data"}}
+		}
+		return startSequence(state, lines, source.Source, key)
+	}
+
+	words := filterWords(readLines(source.Data), state.Locale.WordPattern(),
+		state.Limits.MinWord, state.Limits.MaxWord)
+	if len(words) == 0 {
+		return state, []Command{Exit{GoodbyeMessage: "datafile contains no usable data"}}
+	}
+
+	state, words = withoutSkipped(state, source.Source, words)
+	state.Words = words
+	if state.DrillKeys != "" {
+		state.UnlockedKeys = state.DrillKeys
+	}
+
+	return startRandom(state, wordPhrase(state), source.Source, words, now)
+}
+
+func reduceCodeTree(state State, tree CodeTree) (State, []Command) {
+	lines, hash := treeCode(state, tree)
+	if len(lines) == 0 {
+		return state, []Command{Exit{GoodbyeMessage: "no usable source files found"}}
+	}
+
+	return startSequence(state, lines, tree.Source, positionKey(tree.Source, hash))
+}
+
+// treeCode returns the phrases of all usable source files in tree and a hash
+// of the files.
+func treeCode(state State, tree CodeTree) ([]string, []byte) {
+	var lines []string
+	hash := sha256.New()
+
+	for _, file := range tree.Files {
+		lang, ok := languageFor(file.Path)
+		if !ok || isBinary(file.Data) || isGenerated(file.Path, file.Data) {
+			continue
+		}
+		lines = append(lines, extractCode(state, lang, file.Data)...)
+		hash.Write([]byte(file.Path))
+		hash.Write(file.Data)
+	}
+
+	return lines, hash.Sum(nil)
+}
+
+// fileCode returns the phrases of a single file of code, which is read
+// line by line if its language is unknown.
+func fileCode(state State, source Datasource) []string {
+	if lang, ok := languageFor(source.Source); ok || state.Blocks {
+		return extractCode(state, lang, source.Data)
+	}
+	return filterWords(readLines(source.Data), `^[^/][^/]`, 2, state.maxLine())
+}
+
+// extractCode returns the phrases of a source file, either single lines or
+// whole blocks.
+func extractCode(state State, lang Language, data []byte) []string {
+	if state.Blocks {
+		return codeBlocks(lang, data, maxBlockLines, state.maxLine())
+	}
+	return codeLines(lang, data, state.maxLine())
+}
+
+func reduceGitHistory(state State, history GitHistory) (State, []Command) {
+	lines := gitLines(history, state.maxLine())
+	if len(lines) == 0 {
+		return state, []Command{Exit{GoodbyeMessage: "git history contains no usable data"}}
+	}
+
+	return startSequence(state, lines, history.Source, positionKey(history.Source, history.Data))
+}
+
+func reduceShellHistory(state State, history ShellHistory, now time.Time) (State, []Command) {
+	lines := historyLines(history, state.maxLine())
+	if len(lines) == 0 {
+		return state, []Command{Exit{GoodbyeMessage: "shell history contains no usable commands"}}
+	}
+
+	if !state.HistoryRandom || state.Ramp {
+		return startSequence(state, lines, "history", "")
+	}
+
+	state, lines = withoutSkipped(state, "history", lines)
+	return startRandom(state, RandomLine(lines), "history", lines, now)
+}
+
+func reduceSentenceData(state State, data SentenceData, now time.Time) (State, []Command) {
+	grammar, err := parseGrammar(data.Data)
+	if err != nil {
+		return state, []Command{Exit{GoodbyeMessage: err.Error()}}
+	}
+
+	return startRandom(state, SentencePhrase(grammar, state.Limits.MinPhrase, state.maxPhrase()),
+		data.Source, grammar.Material(), now)
+}
+
+func reduceDrillData(state State, data DrillData, now time.Time) (State, []Command) {
+	return startRandom(state, DrillPhrase(data.Formats, state.Limits.MinPhrase, state.maxPhrase()),
+		"drill:"+strings.Join(data.Formats, ","), data.Formats, now)
+}
+
+func reducePlaylistFile(state State, file PlaylistFile) (State, []Command) {
+	entries, err := parsePlaylist(file.Data)
+	if err != nil {
+		return state, []Command{Exit{GoodbyeMessage: err.Error()}}
 	}
 
-	items := filter(readLines(data))
+	return usePlaylist(state, entries, file.Dir)
+}
+
+// usePlaylist loads the material of all entries, relative paths are
+// resolved against dir.
+func usePlaylist(state State, entries []PlaylistEntry, dir string) (State, []Command) {
+	var commands []Command
+	for i, entry := range entries {
+		if err := entry.mergeLimits(state.Limits).validate(); err != nil {
+			return state, []Command{Exit{GoodbyeMessage: fmt.Sprintf("playlist source %s: %v", entry.Kind, err)}}
+		}
+
+		var load Command
+		switch entry.Kind {
+		case "dictionary":
+			load = LoadBuiltinDictionary{Lang: entry.Arg}
+		case "sentences":
+			load = LoadBuiltinSentences{Lang: entry.Arg}
+		case "drill":
+			formats, _ := parseDrillFormats(entry.Arg)
+			load = LoadBuiltinDrill{Formats: formats}
+		case "lesson":
+			load = LoadBuiltinLesson{Name: entry.Arg}
+		case "history":
+			load = state.HistoryFiles
+		case "git":
+			entry.Arg = resolvePath(entry.Arg, dir)
+			load = ReadGitHistory{Path: entry.Arg, Source: entry.Source(), Count: 100}
+		default:
+			entry.Arg = resolvePath(entry.Arg, dir)
+			load = readDatasource(entry.Arg, entry.Arg, entry.Kind == "code" || entry.Kind == "blocks")
+		}
+		entries[i] = entry
+		commands = append(commands, LoadPlaylistEntry{Index: i, Load: load})
+	}
+
+	state.Playlist = entries
+	state.PlaylistMaterial = make([][]string, len(entries))
+	return state, commands
+}
+
+func resolvePath(path, dir string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(dir, path)
+}
+
+func reducePlaylistData(state State, data PlaylistData, now time.Time) (State, []Command) {
+	entry := state.Playlist[data.Index]
+	items := playlistMaterial(state, entry, data.Message)
 	if len(items) == 0 {
+		return state, []Command{Exit{GoodbyeMessage: fmt.Sprintf(
+			"playlist source %s %s contains no usable data", entry.Kind, entry.Arg)}}
+	}
+	material := append([][]string{}, state.PlaylistMaterial...)
+	material[data.Index] = items
+	state.PlaylistMaterial = material
+
+	for _, items := range material {
+		if items == nil { // still loading
+			return state, Noop
+		}
+	}
+	return startPlaylist(state, now)
+}
+
+// playlistMaterial extracts the words or phrases of an entry like a single
+// source would, with the limits of the entry.
+func playlistMaterial(state State, entry PlaylistEntry, message Message) []string {
+	state.Limits = entry.mergeLimits(state.Limits)
+	state.Blocks = entry.Kind == "blocks"
+
+	switch m := message.(type) {
+	case Datasource:
+		switch entry.Kind {
+		case "dictionary", "words":
+			locale := state.Locale
+			if entry.Kind == "dictionary" {
+				locale = locales[entry.Arg]
+			}
+			return filterWords(readLines(m.Data), locale.WordPattern(),
+				state.Limits.MinWord, state.Limits.MaxWord)
+		case "prose":
+			return splitProse(string(m.Data), state.maxPhrase())
+		}
+		return fileCode(state, m)
+	case LessonData:
+		lesson, err := parseLesson(m.Name, m.Data)
+		if err != nil {
+			return nil
+		}
+		return filterWords(lesson.Lines, `\S`, 1, state.maxLine())
+	case CodeTree:
+		lines, _ := treeCode(state, m)
+		return lines
+	case SentenceData:
+		grammar, err := parseGrammar(m.Data)
+		if err != nil {
+			return nil
+		}
+		return grammar.Material()
+	case DrillData:
+		return m.Formats
+	case GitHistory:
+		return gitLines(m, state.maxLine())
+	case ShellHistory:
+		return historyLines(m, state.maxLine())
+	}
+	return nil
+}
+
+// playlistSkipKey collects the phrases never to be shown again in any
+// playlist, as the source of a phrase is not known.
+const playlistSkipKey = "playlist"
+
+func startPlaylist(state State, now time.Time) (State, []Command) {
+	var generators []PhraseFunc
+	var weights []int
+	var material []string
+	excluded := false
+
+	for i, entry := range state.Playlist {
+		items := state.PlaylistMaterial[i]
+		if entry.hasPhrases() {
+			state, items = withoutSkipped(state, entry.Source(), items)
+			excluded = excluded || state.Excluded
+			state, items = withoutSkipped(state, playlistSkipKey, items)
+			excluded = excluded || state.Excluded
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		generator, err := playlistGenerator(state, entry, items)
+		if err != nil {
+			return state, []Command{Exit{GoodbyeMessage: fmt.Sprintf("playlist %s %s: %v", entry.Kind, entry.Arg, err)}}
+		}
+		generators = append(generators, generator)
+		weights = append(weights, entry.Weight)
+		material = append(append(material, entry.Kind+" "+entry.Arg), items...)
+	}
+
+	state.Excluded = excluded
+	return startRandom(state, PlaylistPhrase(generators, weights), "", material, now)
+}
+
+func playlistGenerator(state State, entry PlaylistEntry, items []string) (PhraseFunc, error) {
+	state.Limits = entry.mergeLimits(state.Limits)
+	if entry.Kind == "sentences" {
+		grammar, err := parseGrammar([]byte(strings.Join(items, "\n")))
+		if err != nil {
+			return nil, err
+		}
+		return SentencePhrase(grammar, state.Limits.MinPhrase, state.maxPhrase()), nil
+	}
+	if entry.Kind == "drill" {
+		return DrillPhrase(items, state.Limits.MinPhrase, state.maxPhrase()), nil
+	}
+	if entry.Kind != "dictionary" && entry.Kind != "words" {
+		return RandomLine(items), nil
+	}
+
+	numberProb := state.NumberProb
+	if entry.NumberProb != nil {
+		numberProb = *entry.NumberProb
+	}
+	if entry.Keys != "" {
+		return KeyDrillPhrase(items, entry.Keys, state.Limits.MinPhrase, state.maxPhrase(),
+			drillNumberProb(entry.Keys, numberProb)), nil
+	}
+	return RandomPhrase(items, state.Limits.MinPhrase, state.maxPhrase(), numberProb), nil
+}
+
+func reduceProse(state State, source Datasource, key string) (State, []Command) {
+	phrases := splitProse(string(source.Data), state.maxPhrase())
+	if len(phrases) == 0 {
 		return state, []Command{Exit{GoodbyeMessage: "datafile contains no usable data"}}
 	}
 
-	state.PhraseGenerator = generator(items)
+	state.ProseSource = source
+	return startSequence(state, phrases, source.Source, key)
+}
+
+// reduceTerminalSize records the new width. Prose split to fit the old
+// width is split again, resuming at the phrase that holds the word being
+// practiced.
+func reduceTerminalSize(state State, size TerminalSize) (State, []Command) {
+	width := state.maxPhrase()
+	state.Width = size.Width
+	if state.ProseSource.Data == nil || state.Replay != nil || state.maxPhrase() == width {
+		return state, Noop
+	}
+
+	words := 0
+	for _, line := range state.Lines[:state.Seed] {
+		words += len(strings.Fields(line))
+	}
+
+	startLine := state.StartLine
+	key := positionKey(state.ProseSource.Source, state.ProseSource.Data)
+	state.StartLine = 1
+	resplit, cmds := reduceProse(state, state.ProseSource, key)
+	if len(cmds) > 0 {
+		return resplit, cmds
+	}
+	state.StartLine = wordLine(resplit.Lines, words) + 1
+	state, cmds = reduceProse(state, state.ProseSource, key)
+	state.StartLine = startLine
+	return state, cmds
+}
+
+// wordLine returns the index of the line holding the word at offset words.
+func wordLine(lines []string, words int) int {
+	for i, line := range lines {
+		words -= len(strings.Fields(line))
+		if words < 0 {
+			return i
+		}
+	}
+	return len(lines) - 1
+}
+
+// startSequence goes through lines in order, beginning with the line
+// requested on the command line or the one remembered for key.
+func startSequence(state State, lines []string, source, key string) (State, []Command) {
+	state, lines = withoutSkipped(state, source, lines)
+	if len(lines) == 0 {
+		return state, []Command{Exit{GoodbyeMessage: allSkipped}}
+	}
+	if !state.Difficulty.IsZero() || state.Ramp {
+		lines = byDifficulty(lines, state.difficulty, state.Difficulty, state.Ramp)
+		key = "" // positions refer to the lines in their original order
+	}
+	if len(lines) == 0 {
+		return state, []Command{Exit{GoodbyeMessage: noneInDifficulty}}
+	}
+	if state.LayoutCheck {
+		return reportLayoutCheck(state, lines)
+	}
+
+	start := 0
+	if state.StartLine > 0 {
+		start = min(state.StartLine, len(lines)) - 1
+	} else if !state.Restart && key != "" {
+		start = state.Positions[key]
+	}
+	if start < 0 || start >= len(lines) {
+		start = 0
+	}
+
+	state.Lines = lines
+	state.PositionKey = key
+	state.PhraseGenerator = SequentialLine(lines)
+	state.Seed = int64((start + len(lines) - 1) % len(lines)) // resetPhrase advances
+	state.Session = Session{
+		Source:     sessionSource(source),
+		Hash:       materialHash(lines),
+		Line:       start + 1,
+		Limits:     state.sessionLimits(),
+		Difficulty: state.Difficulty,
+		Ramp:       state.Ramp,
+		Layout:     state.sessionLayout(),
+	}
+	if state.Excluded {
+		state.Session = Session{}
+	}
 
-	return resetPhrase(state, false), Noop
+	return checkReplay(resetPhrase(state, false))
+}
+
+// startRandom picks phrases with generator, starting from a fixed or random
+// seed, and records the session reproducing them.
+func startRandom(state State, generator PhraseFunc, source string, material []string, now time.Time) (State, []Command) {
+	if state.Ramp {
+		return state, []Command{Exit{GoodbyeMessage: rampNeedsLines}}
+	}
+	if len(material) == 0 {
+		return state, []Command{Exit{GoodbyeMessage: allSkipped}}
+	}
+
+	state.PhraseGenerator = state.withDifficulty(generator)
+	state.Seed = state.FixedSeed
+	if state.Seed == 0 {
+		state.Seed = now.UnixNano()
+	}
+	if state.LayoutCheck {
+		var phrases []string
+		for seed, i := state.Seed, 0; i < layoutCheckSample; i++ {
+			var phrase string
+			seed, phrase = state.PhraseGenerator(seed)
+			phrases = append(phrases, phrase)
+		}
+		return reportLayoutCheck(state, phrases)
+	}
+	state.Session = Session{
+		Source:     sessionSource(source),
+		Hash:       materialHash(material),
+		Seed:       state.Seed,
+		Keys:       state.UnlockedKeys,
+		Limits:     state.sessionLimits(),
+		NumberProb: state.NumberProb,
+		Difficulty: state.Difficulty,
+		Layout:     state.sessionLayout(),
+	}
+	if state.Excluded {
+		state.Session = Session{}
+	}
+
+	state.PhraseCount = 0
+	return checkReplay(resetPhrase(state, false))
+}
+
+const allSkipped = "all phrases of this source are marked never to be shown again, see ~/.gotypist.skips"
+
+const rampNeedsLines = "-ramp needs lines to go through: files, lessons, git or shell history, not random words, sentences, drills or playlists"
+
+const noneInDifficulty = "no phrases of this source lie within the difficulty range"
+
+// withoutSkipped leaves out the phrases or words of source never to be
+// shown again. Sessions with a fixed seed include them to be reproducible,
+// and sessions leaving something out can't be shared.
+func withoutSkipped(state State, source string, items []string) (State, []string) {
+	state.SkipKey = skipKey(source)
+	if state.FixedSeed != 0 || state.Replay != nil {
+		return state, items
+	}
+
+	kept := state.Skips[state.SkipKey].Without(items)
+	state.Excluded = len(kept) < len(items)
+	return state, kept
+}
+
+const cannotNever = "phrases of this source can't be marked never to be shown again"
+
+// reduceNever marks the current phrase, or the word at the cursor when
+// phrases are made up of words, never to be shown again and moves on.
+func reduceNever(s State, now time.Time) (State, []Command) {
+	if s.SkipKey == "" || s.Exec {
+		s.Notice = cannotNever
+		s.NoticeUntil = now.Add(NoticeDuration)
+		return s, []Command{Interrupt{NoticeDuration}}
+	}
+
+	item := s.Phrase.Text
+	if s.Words != nil {
+		item = wordAt(s.Phrase.Text, len(s.Phrase.Input))
+	}
+
+	s, cmds := saveSkips(s, s.Skips.WithNever(s.SkipKey, item))
+	if s.FixedSeed == 0 && s.Replay == nil {
+		var left bool
+		if s, left = leaveOut(s, item); !left {
+			return s, append(cmds, Exit{GoodbyeMessage: allSkipped})
+		}
+	}
+
+	s, nextCmds := advancePhrase(s, true)
+	return s, append(cmds, nextCmds...)
+}
+
+// leaveOut drops item from the phrases still to come and tells whether any
+// are left. Like at the start, a session leaving something out can't be
+// shared.
+func leaveOut(s State, item string) (State, bool) {
+	never := SkipList{Never: []string{item}}
+	s.Excluded = true
+	s.Session = Session{}
+
+	switch {
+	case s.Words != nil:
+		s.Words = never.Without(s.Words)
+		if len(s.Words) == 0 {
+			return s, false
+		}
+		s.PhraseGenerator = s.withDifficulty(wordPhrase(s))
+	case s.Lines != nil:
+		i := int(s.Seed)
+		s.Lines = append(s.Lines[:i:i], s.Lines[i+1:]...)
+		if len(s.Lines) == 0 {
+			return s, false
+		}
+		s.PhraseGenerator = SequentialLine(s.Lines)
+		s.Seed = int64((i + len(s.Lines) - 1) % len(s.Lines)) // resetPhrase advances
+	default:
+		s.PhraseGenerator = Excluding(s.PhraseGenerator, item)
+	}
+
+	return s, true
+}
+
+// recordSkip counts a skip of the current phrase. Skips are only kept in
+// memory until the never-show list changes, to not write the file on every
+// Ctrl-F.
+func recordSkip(state State) State {
+	if state.SkipKey != "" && state.Skipsfile != "" {
+		state.Skips = state.Skips.WithSkipped(state.SkipKey, state.Phrase.Text)
+	}
+	return state
+}
+
+func saveSkips(state State, skips Skips) (State, []Command) {
+	if state.SkipKey == "" || state.Skipsfile == "" {
+		return state, Noop
+	}
+
+	state.Skips = skips
+	return state, []Command{WriteFile{
+		Filename: state.Skipsfile,
+		Data:     formatSkips(state.Skips),
+		Error:    PassError,
+	}}
+}
+
+// checkReplay makes sure that a session given on the command line is
+// replayed with the same material.
+func checkReplay(state State) (State, []Command) {
+	if state.Replay != nil && state.Replay.Hash != state.Session.Hash {
+		return state, []Command{Exit{GoodbyeMessage: "the session was made with different material, " +
+			"pass the same file and options as its creator"}}
+	}
+	return state, Noop
+}
+
+// savePosition remembers the current line of a sequential source.
+func savePosition(state State) (State, []Command) {
+	if state.PositionKey == "" || state.Positionsfile == "" {
+		return state, Noop
+	}
+
+	state.Positions = state.Positions.With(state.PositionKey, int(state.Seed))
+	return state, []Command{WriteFile{
+		Filename: state.Positionsfile,
+		Data:     formatPositions(state.Positions),
+		Error:    PassError,
+	}}
+}
+
+func reduceLessonData(state State, name, source string, data []byte, now time.Time) (State, []Command) {
+	lesson, err := parseLesson(name, data)
+	if err != nil {
+		return state, []Command{Exit{GoodbyeMessage: err.Error()}}
+	}
+
+	return useLesson(state, lesson, source, now)
+}
+
+func reduceTypData(state State, typ TypData, now time.Time) (State, []Command) {
+	lesson, err := parseTyp(typ.Name, typ.Data, typ.Label)
+	if err != nil {
+		return state, []Command{Exit{GoodbyeMessage: err.Error()}}
+	}
+
+	if typ.Convert {
+		return state, []Command{Exit{GoodbyeMessage: strings.TrimSuffix(string(formatLesson(lesson)), "\n")}}
+	}
+
+	return useLesson(state, lesson, typ.Source, now)
+}
+
+func useLesson(state State, lesson Lesson, source string, now time.Time) (State, []Command) {
+	lines := filterWords(lesson.Lines, `\S`, 1, state.maxLine())
+	if len(lines) == 0 {
+		return state, []Command{Exit{GoodbyeMessage: "lesson contains no usable data"}}
+	}
+
+	state.Lesson = &lesson
+	state.Modes = lesson.Modes
+	if lesson.Order == OrderSequential || state.Ramp {
+		return startSequence(state, lines, source, "")
+	}
+
+	state, lines = withoutSkipped(state, source, lines)
+	return startRandom(state, RandomLine(lines), source, lines, now)
+}
+
+func reduceLayoutData(state State, data LayoutData) (State, []Command) {
+	layout, err := parseLayout(data.Name, data.Data)
+	if err != nil {
+		return state, []Command{Exit{GoodbyeMessage: err.Error()}}
+	}
+
+	if state.Analysis != nil {
+		analysis := make([]Layout, len(state.Analysis))
+		for i, l := range state.Analysis {
+			analysis[i] = l
+			if l.Name == layout.Name {
+				analysis[i] = layout
+			}
+		}
+		state.Analysis = analysis
+		return state, Noop
+	}
+
+	state.Layout = layout
+	state.FingerMap = state.Locale.Fingers(layout.Fingers())
+	return state, Noop
+}
+
+// reduceCorpusData exits with the result of "layout analyze".
+func reduceCorpusData(state State, corpus CorpusData) (State, []Command) {
+	var analyses []LayoutAnalysis
+	for _, layout := range state.Analysis {
+		analyses = append(analyses, analyzeLayout(layout, string(corpus.Data)))
+	}
+	return state, []Command{Exit{GoodbyeMessage: formatAnalyses(state.Analysis, analyses)}}
+}
+
+// layoutCheckSample is the number of phrases generated to check whether a
+// layout can type all characters of a random source.
+const layoutCheckSample = 1000
+
+// reportLayoutCheck exits with the result of "layout check" for the phrases
+// of a source instead of starting the session.
+func reportLayoutCheck(state State, phrases []string) (State, []Command) {
+	return state, []Command{Exit{GoodbyeMessage: checkLayout(state.Layout, state.Locale, phrases)}}
+}
+
+func reduceCurriculumData(state State, data []byte) (State, []Command) {
+	curriculum, err := parseCurriculum(data)
+	if err != nil {
+		return state, []Command{Exit{GoodbyeMessage: err.Error()}}
+	}
+
+	state.Curriculum = curriculum
+	return reloadCurriculum(state), Noop
+}
+
+// applyCurriculum restricts the words to the keys unlocked at the current
+// level and tells whether the phrase generator changed.
+func applyCurriculum(state State) (State, bool) {
+	if state.Curriculum == nil || state.Words == nil {
+		return state, false
+	}
+
+	n := state.Curriculum.Unlocked(level(state.Score))
+	keys := state.Locale.WithLetters(state.Curriculum.Keys(n))
+	if keys == state.UnlockedKeys {
+		return state, false
+	}
+	if state.FixedSeed != 0 && state.UnlockedKeys != "" {
+		return state, false // keep the session reproducible
+	}
+
+	return unlockKeys(state, keys), true
+}
+
+// reloadCurriculum applies the curriculum once the score or the curriculum
+// itself is loaded. Nothing has been practiced yet, so the session starts
+// over with the keys unlocked.
+func reloadCurriculum(state State) State {
+	next, changed := applyCurriculum(state)
+	if !changed {
+		return state
+	}
+
+	next.Session.Seed = next.Seed
+	next.Session.Keys = next.UnlockedKeys
+	next.Session.Unlocks = nil
+	next.PhraseCount = 0
+	return resetPhrase(next, false)
+}
+
+// unlockKeys drills the words typeable with keys from now on. Keys unlocked
+// after the start of the session are recorded along with the number of the
+// phrase, so that replaying the session unlocks them at the same point.
+func unlockKeys(state State, keys string) State {
+	if state.UnlockedKeys == "" {
+		state.Session.Keys = keys
+	} else {
+		state.Session.Unlocks = append(state.Session.Unlocks, Unlock{Phrase: state.PhraseCount, Keys: keys})
+	}
+
+	state.UnlockedKeys = keys
+	state.PhraseGenerator = state.withDifficulty(wordPhrase(state))
+	return state
+}
+
+// wordPhrase composes phrases of the words typeable with the unlocked keys.
+func wordPhrase(state State) PhraseFunc {
+	if state.UnlockedKeys == "" || state.UnlockedKeys == allKeys {
+		return RandomPhrase(state.Words, state.Limits.MinPhrase, state.maxPhrase(), state.NumberProb)
+	}
+	return KeyDrillPhrase(state.Words, state.UnlockedKeys, state.Limits.MinPhrase, state.maxPhrase(),
+		drillNumberProb(state.UnlockedKeys, state.NumberProb))
+}
+
+// replayUnlocks unlocks keys at the phrases the replayed session did.
+func replayUnlocks(state State) State {
+	if state.Replay == nil {
+		return state
+	}
+
+	for _, unlock := range state.Replay.Unlocks {
+		if unlock.Phrase == state.PhraseCount && unlock.Keys != state.UnlockedKeys {
+			state = unlockKeys(state, unlock.Keys)
+		}
+	}
+	return state
+}
+
+// drillNumberProb returns numberProb if numbers can be typed with keys, and
+// zero otherwise.
+func drillNumberProb(keys string, numberProb float64) float64 {
+	if typeable("0123456789", keys) {
+		return numberProb
+	}
+	return 0
 }
 
 func resetPhrase(state State, forceNext bool) State {
+	state, _ = applyCurriculum(state)
+	state = replayUnlocks(state)
 	if !state.Repeat || forceNext {
 		next, _ := state.PhraseGenerator(state.Seed)
 		state.Seed = next
+		state.PhraseCount++
 	}
 	_, phrase := state.PhraseGenerator(state.Seed)
 	state.Phrase = *NewPhrase(phrase)
+	state.Phrase.Mode = state.modes()[0]
 
 	return state
 }
 
+// difficulty scores phrase with the finger hints and the layout in use.
+func (s State) difficulty(phrase string) float64 {
+	return phraseDifficulty(phrase, s.FingerMap, s.Layout.Keys).Score
+}
+
+// withDifficulty makes generator stick to the difficulty range, if any.
+func (s State) withDifficulty(generator PhraseFunc) PhraseFunc {
+	if s.Difficulty.IsZero() {
+		return generator
+	}
+	return DifficultyFilter(generator, s.difficulty, s.Difficulty)
+}
+
+// sessionLayout returns the layout when it decides about the phrases.
+func (s State) sessionLayout() string {
+	if s.Difficulty.IsZero() && !s.Ramp {
+		return ""
+	}
+	return s.Layout.Name
+}
+
+func (s State) maxPhrase() int {
+	if s.Limits.MaxPhrase > 0 {
+		return s.Limits.MaxPhrase
+	}
+	return s.availableWidth()
+}
+
+// sessionLimits are the limits in effect, independent of the terminal width.
+func (s State) sessionLimits() Limits {
+	limits := s.Limits
+	limits.MaxPhrase = s.maxPhrase()
+	limits.MaxLine = s.maxLine()
+	return limits
+}
+
+func (s State) maxLine() int {
+	if s.Limits.MaxLine > 0 {
+		return s.Limits.MaxLine
+	}
+	return s.availableWidth()
+}
+
+// availableWidth is the width a phrase can take up on screen, with room left
+// for the return symbol.
+func (s State) availableWidth() int {
+	if s.Width > 2 {
+		return s.Width - 2
+	}
+	return 80
+}
+
+func (s State) modes() []Mode {
+	if len(s.Modes) == 0 {
+		return ModeCycle
+	}
+	return s.Modes
+}
+
+// cursorPosition returns line and column (in runes) of a byte offset in text.
+func cursorPosition(text string, byteOffset int) (int, int) {
+	before := text[:byteOffset]
+	line := strings.Count(before, "\n")
+	col := utf8.RuneCountInString(before[strings.LastIndex(before, "\n")+1:])
+	return line, col
+}
+
 func errorOffset(text string, input string) (int, int) {
 	runeOffset := 0
 	for i, tr := range text {
@@ -259,7 +1203,9 @@ func NewState(seed int64, phraseGenerator PhraseFunc) *State {
 	s := resetPhrase(State{
 		PhraseGenerator: phraseGenerator,
 		Seed:            seed,
-		HideFingers:     true,
+		Locale:          locales["en"],
+		Layout:          layouts["qwerty"],
+		FingerMap:       layouts["qwerty"].Fingers(),
 	}, false)
 
 	return &s
@@ -288,6 +1234,10 @@ func (p *Phrase) ErrorCountColor(t time.Time) termbox.Attribute {
 	return termbox.ColorDefault
 }
 
+func (p *Phrase) IsMultiline() bool {
+	return strings.Contains(p.Text, "\n")
+}
+
 func (p *Phrase) expected() rune {
 	if len(p.Input) >= len(p.Text) {
 		return 0
@@ -308,5 +1258,9 @@ func banner(s State, t time.Time) string {
 `
 	}
 
+	if !s.Session.IsZero() {
+		return "Replay this session with: gotypist -session " + formatSession(s.Session)
+	}
+
 	return ""
 }
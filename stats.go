@@ -17,6 +17,7 @@ type Statistics struct {
 	FinishedAt time.Time `json:"finished_at"`
 	Errors     int       `json:"errors"`
 	Typos      []Typo    `json:"typos"`
+	ShiftSlips int       `json:"shift_slips,omitempty"`
 	Mode       Mode      `json:"mode"`
 	Seconds    float64   `json:"seconds"`
 	CPS        float64   `json:"cps"`
@@ -102,6 +103,7 @@ func formatStats(phrase *Phrase, now time.Time) []byte {
 		FinishedAt: now,
 		Errors:     phrase.CurrentRound().Errors,
 		Typos:      typos,
+		ShiftSlips: phrase.CurrentRound().ShiftSlips,
 		Mode:       phrase.Mode,
 		Seconds:    seconds,
 		CPS:        cps,
@@ -0,0 +1,115 @@
+// only pure code in this file (no side effects)
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// typ command characters, see the GNU Typist manual for the full list.
+const (
+	typBanner            = 'B'
+	typDrill             = 'D'
+	typDrillPractice     = 'd'
+	typSpeedTest         = 'S'
+	typSpeedTestPractice = 's'
+	typInstruction       = 'I'
+	typLabel             = '*'
+	typComment           = '#'
+	typContinue          = ' '
+)
+
+// typStatement is a single command of a .typ script including all of its
+// continuation lines.
+type typStatement struct {
+	Command rune
+	Lines   []string
+}
+
+// parseTyp converts a GNU Typist .typ script into a lesson. Drills and speed
+// tests are turned into phrases, one per line; the first banner becomes the
+// title and the first instruction the description. All other commands
+// (tutorials, menus, jumps, queries, ...) are ignored. If label is not empty,
+// only the part of the script after that label is converted.
+func parseTyp(name string, data []byte, label string) (Lesson, error) {
+	lesson := Lesson{Name: name, Title: name}
+
+	statements, err := parseTypStatements(readLines(data))
+	if err != nil {
+		return lesson, fmt.Errorf("%s: %v", name, err)
+	}
+
+	if label != "" {
+		found := false
+		for i, st := range statements {
+			if st.Command == typLabel && strings.TrimSpace(st.Lines[0]) == label {
+				statements = statements[i+1:]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return lesson, fmt.Errorf("%s: label %q not found", name, label)
+		}
+	}
+
+	haveTitle, haveDescription := false, false
+	for _, st := range statements {
+		switch st.Command {
+		case typBanner:
+			if !haveTitle {
+				lesson.Title = strings.TrimSpace(st.Lines[0])
+				haveTitle = true
+			}
+		case typInstruction:
+			if !haveDescription {
+				lesson.Description = strings.TrimSpace(strings.Join(st.Lines, " "))
+				haveDescription = true
+			}
+		case typDrill, typDrillPractice, typSpeedTest, typSpeedTestPractice:
+			for _, line := range st.Lines {
+				if trimmed := strings.TrimSpace(line); trimmed != "" {
+					lesson.Lines = append(lesson.Lines, trimmed)
+				}
+			}
+		}
+	}
+
+	if len(lesson.Lines) == 0 {
+		return lesson, fmt.Errorf("%s: no drills or speed tests found", name)
+	}
+
+	return lesson, nil
+}
+
+func parseTypStatements(lines []string) ([]typStatement, error) {
+	var statements []typStatement
+
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || line[0] == typComment {
+			continue
+		}
+
+		if len(line) < 2 || line[1] != ':' {
+			return nil, fmt.Errorf("line %d: expected \"C:text\"", i+1)
+		}
+
+		command, text := rune(line[0]), line[2:]
+		if command == typContinue {
+			if len(statements) == 0 {
+				return nil, fmt.Errorf("line %d: continuation without command", i+1)
+			}
+			last := &statements[len(statements)-1]
+			last.Lines = append(last.Lines, text)
+			continue
+		}
+
+		statements = append(statements, typStatement{
+			Command: command,
+			Lines:   []string{text},
+		})
+	}
+
+	return statements, nil
+}
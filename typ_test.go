@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const typScript = `# a small lesson
+G:_L1
+*:_L1
+B:     Lesson Q1
+I:Home row keys
+ :for both hands
+T:Place your fingers on the home row.
+ :
+D:asdf jkl;
+ :fdsa ;lkj
+*:_L2
+B:Speed
+S:all lads had a flask
+ :a sad lass
+d:practice only
+Q:Do you want to continue? [Y/N]
+`
+
+func TestParseTyp(t *testing.T) {
+	lesson, err := parseTyp("q.typ", []byte(typScript), "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Lesson Q1", lesson.Title)
+	assert.Equal(t, "Home row keys for both hands", lesson.Description)
+	assert.Equal(t, []string{
+		"asdf jkl;", "fdsa ;lkj", "all lads had a flask", "a sad lass", "practice only",
+	}, lesson.Lines)
+}
+
+func TestParseTypLabel(t *testing.T) {
+	lesson, err := parseTyp("q.typ", []byte(typScript), "_L2")
+	assert.NoError(t, err)
+	assert.Equal(t, "Speed", lesson.Title)
+	assert.Equal(t, []string{"all lads had a flask", "a sad lass", "practice only"}, lesson.Lines)
+
+	_, err = parseTyp("q.typ", []byte(typScript), "_L3")
+	assert.Error(t, err)
+}
+
+func TestParseTypMalformed(t *testing.T) {
+	_, err := parseTyp("bad.typ", []byte(" :dangling\n"), "")
+	assert.Error(t, err)
+
+	_, err = parseTyp("bad.typ", []byte("no colon here\n"), "")
+	assert.Error(t, err)
+}
+
+func TestConvertTyp(t *testing.T) {
+	lesson, err := parseTyp("q.typ", []byte(typScript), "_L2")
+	assert.NoError(t, err)
+
+	converted, err := parseLesson("q", formatLesson(lesson))
+	assert.NoError(t, err)
+	assert.Equal(t, lesson.Title, converted.Title)
+	assert.Equal(t, lesson.Lines, converted.Lines)
+}